@@ -1,19 +1,85 @@
 package slogex
 
-import "log/slog"
+import (
+	"fmt"
+	"log/slog"
+)
 
 // DefaultErrorKey is the default error key.
 const DefaultErrorKey = "error"
 
+// DefaultErrorTypeKey is the default error type key.
+const DefaultErrorTypeKey = "error_type"
+
+// ErrorMessageKey is the error message key inside the error group, see ErrorWithType and ErrorGroup.
+const ErrorMessageKey = "msg"
+
+// ErrorGroupTypeKey is the error type key inside the error group, see ErrorWithType.
+const ErrorGroupTypeKey = "type"
+
 // ErrorKey is the error key that is used by the package. User may set it to own value on the package level.
 var ErrorKey = DefaultErrorKey
 
-// Error returns slog attribute with error key.
+// ErrorTypeKey is the error type key that is used by the package. User may set it to own value on the package level.
+var ErrorTypeKey = DefaultErrorTypeKey
+
+// Error returns slog attribute with error key and ErrorValue as a value.
 func Error(err error) slog.Attr {
 	if err == nil {
 		// return empty attr so that logger will filter this field out, like zap does
 		return slog.Attr{}
 	}
 
-	return slog.String(ErrorKey, err.Error())
+	return slog.Attr{Key: ErrorKey, Value: ErrorValue(err)}
+}
+
+// ErrorValue returns slog value for the error, so that it can be put under an arbitrary key.
+// If the error implements slog.LogValuer then its resolved value is used, otherwise error message.
+// Zero value is returned for nil error.
+func ErrorValue(err error) slog.Value {
+	if err == nil {
+		return slog.Value{}
+	}
+
+	if lv, ok := err.(slog.LogValuer); ok {
+		return lv.LogValue().Resolve()
+	}
+
+	return slog.StringValue(err.Error())
+}
+
+// ErrorType returns slog attribute with error type key and the concrete Go type of the error as a value.
+func ErrorType(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+
+	return slog.String(ErrorTypeKey, fmt.Sprintf("%T", err))
+}
+
+// ErrorWithType returns slog group attribute with error key that contains both error message and error type.
+func ErrorWithType(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+
+	return slog.Group(ErrorKey,
+		slog.String(ErrorMessageKey, err.Error()),
+		slog.String(ErrorGroupTypeKey, fmt.Sprintf("%T", err)),
+	)
+}
+
+// ErrorGroup returns slog group attribute with error key that contains error message and extra attributes,
+// e.g. operation or resource ID, so that error metadata is namespaced together with the error.
+func ErrorGroup(err error, extra ...slog.Attr) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+
+	attrs := make([]any, 0, len(extra)+1)
+	attrs = append(attrs, slog.String(ErrorMessageKey, err.Error()))
+	for _, a := range extra {
+		attrs = append(attrs, a)
+	}
+	return slog.Group(ErrorKey, attrs...)
 }
@@ -0,0 +1,58 @@
+package slogex
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// DefaultErrorTallyKey is the default error tally key.
+const DefaultErrorTallyKey = "errors"
+
+// ErrorTallyKey is the error tally key that is used by the package. User may set it to own value on the package level.
+var ErrorTallyKey = DefaultErrorTallyKey
+
+// ErrorTally accumulates error counts per error fingerprint, that is error message.
+// It is useful for logging a single summary of all errors seen, e.g. in a batch processing.
+// ErrorTally is safe for concurrent use. Zero value is ready to use.
+type ErrorTally struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// Add accounts the error in the tally. Nil errors are ignored.
+func (t *ErrorTally) Add(err error) {
+	if err == nil {
+		return
+	}
+
+	t.mu.Lock()
+	if t.counts == nil {
+		t.counts = make(map[string]int)
+	}
+	t.counts[err.Error()]++
+	t.mu.Unlock()
+}
+
+// Attr returns slog group attribute with error tally key that contains error fingerprint to count pairs
+// sorted by fingerprint. Empty attribute is returned if no errors were added.
+func (t *ErrorTally) Attr() slog.Attr {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.counts) == 0 {
+		return slog.Attr{}
+	}
+
+	fingerprints := make([]string, 0, len(t.counts))
+	for fp := range t.counts {
+		fingerprints = append(fingerprints, fp)
+	}
+	sort.Strings(fingerprints)
+
+	attrs := make([]any, 0, len(fingerprints))
+	for _, fp := range fingerprints {
+		attrs = append(attrs, slog.Int(fp, t.counts[fp]))
+	}
+	return slog.Group(ErrorTallyKey, attrs...)
+}
@@ -0,0 +1,37 @@
+package slogex
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorTally(t *testing.T) {
+	var tally ErrorTally
+	assert.Equal(t, slog.Attr{}, tally.Attr())
+
+	errTimeout := errors.New("timeout")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tally.Add(errTimeout)
+		}()
+	}
+	wg.Wait()
+
+	tally.Add(fmt.Errorf("not found: %d", 42))
+	tally.Add(errors.New("timeout"))
+	tally.Add(nil)
+
+	assert.Equal(t, slog.Group("errors",
+		slog.Int("not found: 42", 1),
+		slog.Int("timeout", 4),
+	), tally.Attr())
+}
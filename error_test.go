@@ -0,0 +1,63 @@
+package slogex
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type codeError struct {
+	code      int
+	retryable bool
+}
+
+func (e codeError) Error() string {
+	return fmt.Sprintf("code error %d", e.code)
+}
+
+func (e codeError) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("code", e.code), slog.Bool("retryable", e.retryable))
+}
+
+func TestError(t *testing.T) {
+	assert.Equal(t, slog.Attr{}, Error(nil))
+	assert.Equal(t, slog.String("error", "some error"), Error(errors.New("some error")))
+	assert.Equal(t, slog.Group("error", slog.Int("code", 42), slog.Bool("retryable", true)), Error(codeError{code: 42, retryable: true}))
+	assert.Equal(t, slog.String("error", "wrapped: code error 42"), Error(fmt.Errorf("wrapped: %w", codeError{code: 42})))
+}
+
+func TestErrorValue(t *testing.T) {
+	assert.Equal(t, slog.Value{}, ErrorValue(nil))
+	assert.Equal(t, slog.StringValue("some error"), ErrorValue(errors.New("some error")))
+	assert.Equal(t, slog.GroupValue(slog.Int("code", 42), slog.Bool("retryable", true)), ErrorValue(codeError{code: 42, retryable: true}))
+	assert.Equal(t, slog.Group("cause", slog.Int("code", 42), slog.Bool("retryable", false)),
+		slog.Attr{Key: "cause", Value: ErrorValue(codeError{code: 42})})
+}
+
+func TestErrorType(t *testing.T) {
+	assert.Equal(t, slog.Attr{}, ErrorType(nil))
+	assert.Equal(t, slog.String("error_type", "*errors.errorString"), ErrorType(errors.New("some error")))
+	assert.Equal(t, slog.String("error_type", "*fs.PathError"), ErrorType(&fs.PathError{Op: "open", Path: "foo", Err: fs.ErrNotExist}))
+}
+
+func TestErrorWithType(t *testing.T) {
+	assert.Equal(t, slog.Attr{}, ErrorWithType(nil))
+	assert.Equal(t, slog.Group("error",
+		slog.String("msg", "some error"),
+		slog.String("type", "*errors.errorString"),
+	), ErrorWithType(errors.New("some error")))
+}
+
+func TestErrorGroup(t *testing.T) {
+	assert.Equal(t, slog.Attr{}, ErrorGroup(nil, slog.String("op", "read")))
+	assert.Equal(t, slog.Group("error", slog.String("msg", "some error")), ErrorGroup(errors.New("some error")))
+	assert.Equal(t, slog.Group("error",
+		slog.String("msg", "some error"),
+		slog.String("op", "read"),
+		slog.Int("id", 42),
+	), ErrorGroup(errors.New("some error"), slog.String("op", "read"), slog.Int("id", 42)))
+}
@@ -2,8 +2,11 @@ package fxlogger
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"runtime/debug"
 	"strings"
+	"sync"
 
 	"go.uber.org/fx/fxevent"
 
@@ -12,10 +15,97 @@ import (
 
 // Logger is an Fx event logger that logs events to log/slog.
 type Logger struct {
+	// Logger is the logger that events are logged to, slog.Default is used if it is nil.
 	Logger *slog.Logger
 
 	logLevel   slog.Level // default: slog.LevelInfo
 	errorLevel *slog.Level
+	eventAttrs func(fxevent.Event) []slog.Attr
+	attrs      []slog.Attr // attributes of the event being logged, see UseEventAttrs
+	errors     *errorStats
+
+	noTraces      bool
+	invokingStack bool
+	nameRedactor  func(string) string
+	fieldNames    *FieldNames // resolved in UseFieldNames, nil means the defaults
+}
+
+// FieldNames are the attribute keys used for the Fx event logs. Empty names fall back to the defaults,
+// see DefaultFieldNames. Empty Error falls back to slogex.ErrorKey.
+type FieldNames struct {
+	Callee      string
+	Caller      string
+	Runtime     string
+	Type        string
+	StackTrace  string
+	ModuleTrace string
+	Module      string
+	Constructor string
+	Private     string
+	Decorator   string
+	Name        string
+	Kind        string
+	Function    string
+	Stack       string
+	Signal      string
+	Event       string
+	Error       string
+}
+
+// DefaultFieldNames returns the attribute keys that are used unless UseFieldNames is called.
+func DefaultFieldNames() FieldNames {
+	return FieldNames{
+		Callee:      "callee",
+		Caller:      "caller",
+		Runtime:     "runtime",
+		Type:        "type",
+		StackTrace:  "stacktrace",
+		ModuleTrace: "moduletrace",
+		Module:      "module",
+		Constructor: "constructor",
+		Private:     "private",
+		Decorator:   "decorator",
+		Name:        "name",
+		Kind:        "kind",
+		Function:    "function",
+		Stack:       "stack",
+		Signal:      "signal",
+		Event:       "fx_event",
+	}
+}
+
+// defaultFieldNames are the attribute keys used unless UseFieldNames is called.
+var defaultFieldNames = DefaultFieldNames()
+
+// withDefaults returns a copy of the names with the empty ones replaced by the defaults.
+func (n FieldNames) withDefaults() FieldNames {
+	d := DefaultFieldNames()
+	return FieldNames{
+		Callee:      orDefault(n.Callee, d.Callee),
+		Caller:      orDefault(n.Caller, d.Caller),
+		Runtime:     orDefault(n.Runtime, d.Runtime),
+		Type:        orDefault(n.Type, d.Type),
+		StackTrace:  orDefault(n.StackTrace, d.StackTrace),
+		ModuleTrace: orDefault(n.ModuleTrace, d.ModuleTrace),
+		Module:      orDefault(n.Module, d.Module),
+		Constructor: orDefault(n.Constructor, d.Constructor),
+		Private:     orDefault(n.Private, d.Private),
+		Decorator:   orDefault(n.Decorator, d.Decorator),
+		Name:        orDefault(n.Name, d.Name),
+		Kind:        orDefault(n.Kind, d.Kind),
+		Function:    orDefault(n.Function, d.Function),
+		Stack:       orDefault(n.Stack, d.Stack),
+		Signal:      orDefault(n.Signal, d.Signal),
+		Event:       orDefault(n.Event, d.Event),
+		Error:       n.Error,
+	}
+}
+
+func orDefault(name, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
 }
 
 var _ fxevent.Logger = (*Logger)(nil)
@@ -30,189 +120,317 @@ func (l *Logger) UseLogLevel(level slog.Level) {
 	l.logLevel = level
 }
 
+// UseSingleLevel sets the level of all logs emitted by Fx, including errors, to level, e.g. to make
+// Fx quiet by default with slog.LevelDebug. It is the same as calling both UseLogLevel and UseErrorLevel,
+// so the following calls of either of them override the respective level.
+func (l *Logger) UseSingleLevel(level slog.Level) {
+	l.UseLogLevel(level)
+	l.UseErrorLevel(level)
+}
+
+// UseEventAttrs sets the function that is called for every event to get additional attributes
+// that are appended to the event log, e.g. to tag events with a category computed from their type.
+func (l *Logger) UseEventAttrs(fn func(fxevent.Event) []slog.Attr) {
+	l.eventAttrs = fn
+}
+
+// UseTraces sets whether stacktrace and moduletrace attributes are logged, they are logged by default.
+// Disabling them reduces the size of Fx logs significantly, error events still log the error.
+func (l *Logger) UseTraces(enabled bool) {
+	l.noTraces = !enabled
+}
+
+// UseInvokingStack sets whether Invoking events are logged with the stack of the goroutine that invokes
+// the function, it is disabled by default as it makes logs hard to read, but helps to diagnose hanging invokes.
+// fxevent.Invoking does not carry the trace, so the stack is captured when the event is logged.
+func (l *Logger) UseInvokingStack(enabled bool) {
+	l.invokingStack = enabled
+}
+
+// UseNameRedactor sets the function that is applied to function, caller, constructor, decorator
+// and type names before they are logged, e.g. to scrub or shorten them. Names are logged as is if it is nil.
+func (l *Logger) UseNameRedactor(fn func(string) string) {
+	l.nameRedactor = fn
+}
+
+// UseFieldNames sets the attribute keys used for the Fx event logs, e.g. to match the existing log schema.
+// Empty names fall back to the defaults, see DefaultFieldNames.
+func (l *Logger) UseFieldNames(names FieldNames) {
+	names = names.withDefaults()
+	l.fieldNames = &names
+}
+
+// UseErrorTracking enables tracking of the error events logged by Fx, see ErrorCount and LastError.
+func (l *Logger) UseErrorTracking() {
+	l.errors = &errorStats{}
+}
+
+// ErrorCount returns the number of error events logged by Fx since UseErrorTracking was called.
+// It always returns 0 if error tracking is not enabled.
+func (l *Logger) ErrorCount() int {
+	return l.errors.count()
+}
+
+// LastError returns the error of the latest error event logged by Fx since UseErrorTracking was called.
+// It always returns nil if error tracking is not enabled.
+func (l *Logger) LastError() error {
+	return l.errors.last()
+}
+
+// logger returns the Logger, or slog.Default if it is not set, so that the misconfigured logger does not panic.
+func (l *Logger) logger() *slog.Logger {
+	if l.Logger == nil {
+		return slog.Default()
+	}
+	return l.Logger
+}
+
 func (l *Logger) logEvent(msg string, fields ...any) {
-	l.Logger.Log(context.Background(), l.logLevel, msg, fields...)
+	l.logger().Log(context.Background(), l.logLevel, msg, l.withAttrs(fields)...)
 }
 
-func (l *Logger) logError(msg string, fields ...any) {
+func (l *Logger) logError(err error, msg string, fields ...any) {
+	l.errors.add(err)
+
 	lvl := slog.LevelError
 	if l.errorLevel != nil {
 		lvl = *l.errorLevel
 	}
-	l.Logger.Log(context.Background(), lvl, msg, fields...)
+	l.logger().Log(context.Background(), lvl, msg, l.withAttrs(fields)...)
+}
+
+// withAttrs appends the event attributes after the event fields.
+func (l *Logger) withAttrs(fields []any) []any {
+	for _, a := range l.attrs {
+		fields = append(fields, a)
+	}
+	return fields
 }
 
 // LogEvent logs the given event to the provided Zap logger.
 func (l *Logger) LogEvent(event fxevent.Event) {
+	if l.eventAttrs != nil {
+		if attrs := l.eventAttrs(event); len(attrs) > 0 {
+			el := *l
+			el.attrs = attrs
+			el.eventAttrs = nil
+			el.LogEvent(event)
+			return
+		}
+	}
+
+	f := l.names()
+
 	switch e := event.(type) {
 	case *fxevent.OnStartExecuting:
 		l.logEvent("OnStart hook executing",
-			slog.String("callee", e.FunctionName),
-			slog.String("caller", e.CallerName),
+			slog.String(f.Callee, l.name(e.FunctionName)),
+			slog.String(f.Caller, l.name(e.CallerName)),
 		)
 	case *fxevent.OnStartExecuted:
 		if e.Err != nil {
-			l.logError("OnStart hook failed",
-				slog.String("callee", e.FunctionName),
-				slog.String("caller", e.CallerName),
-				slogex.Error(e.Err),
+			l.logError(e.Err, "OnStart hook failed",
+				slog.String(f.Callee, l.name(e.FunctionName)),
+				slog.String(f.Caller, l.name(e.CallerName)),
+				f.errorField(e.Err),
 			)
 		} else {
 			l.logEvent("OnStart hook executed",
-				slog.String("callee", e.FunctionName),
-				slog.String("caller", e.CallerName),
-				slog.String("runtime", e.Runtime.String()),
+				slog.String(f.Callee, l.name(e.FunctionName)),
+				slog.String(f.Caller, l.name(e.CallerName)),
+				slog.String(f.Runtime, e.Runtime.String()),
 			)
 		}
 	case *fxevent.OnStopExecuting:
 		l.logEvent("OnStop hook executing",
-			slog.String("callee", e.FunctionName),
-			slog.String("caller", e.CallerName),
+			slog.String(f.Callee, l.name(e.FunctionName)),
+			slog.String(f.Caller, l.name(e.CallerName)),
 		)
 	case *fxevent.OnStopExecuted:
 		if e.Err != nil {
-			l.logError("OnStop hook failed",
-				slog.String("callee", e.FunctionName),
-				slog.String("caller", e.CallerName),
-				slogex.Error(e.Err),
+			l.logError(e.Err, "OnStop hook failed",
+				slog.String(f.Callee, l.name(e.FunctionName)),
+				slog.String(f.Caller, l.name(e.CallerName)),
+				f.errorField(e.Err),
 			)
 		} else {
 			l.logEvent("OnStop hook executed",
-				slog.String("callee", e.FunctionName),
-				slog.String("caller", e.CallerName),
-				slog.String("runtime", e.Runtime.String()),
+				slog.String(f.Callee, l.name(e.FunctionName)),
+				slog.String(f.Caller, l.name(e.CallerName)),
+				slog.String(f.Runtime, e.Runtime.String()),
 			)
 		}
 	case *fxevent.Supplied:
 		if e.Err != nil {
-			l.logError("error encountered while applying options",
-				slog.String("type", e.TypeName),
-				slog.Any("stacktrace", e.StackTrace),
-				slog.Any("moduletrace", e.ModuleTrace),
-				moduleField(e.ModuleName),
-				slogex.Error(e.Err))
+			l.logError(e.Err, "error encountered while applying options",
+				slog.String(f.Type, l.name(e.TypeName)),
+				l.traceField(f.StackTrace, e.StackTrace),
+				l.traceField(f.ModuleTrace, e.ModuleTrace),
+				moduleField(f.Module, e.ModuleName),
+				f.errorField(e.Err))
 		} else {
 			l.logEvent("supplied",
-				slog.String("type", e.TypeName),
-				slog.Any("stacktrace", e.StackTrace),
-				slog.Any("moduletrace", e.ModuleTrace),
-				moduleField(e.ModuleName),
+				slog.String(f.Type, l.name(e.TypeName)),
+				l.traceField(f.StackTrace, e.StackTrace),
+				l.traceField(f.ModuleTrace, e.ModuleTrace),
+				moduleField(f.Module, e.ModuleName),
 			)
 		}
 	case *fxevent.Provided:
 		for _, rtype := range e.OutputTypeNames {
 			l.logEvent("provided",
-				slog.String("constructor", e.ConstructorName),
-				slog.Any("stacktrace", e.StackTrace),
-				slog.Any("moduletrace", e.ModuleTrace),
-				moduleField(e.ModuleName),
-				slog.String("type", rtype),
-				maybeBool("private", e.Private),
+				slog.String(f.Constructor, l.name(e.ConstructorName)),
+				l.traceField(f.StackTrace, e.StackTrace),
+				l.traceField(f.ModuleTrace, e.ModuleTrace),
+				moduleField(f.Module, e.ModuleName),
+				slog.String(f.Type, l.name(rtype)),
+				maybeBool(f.Private, e.Private),
 			)
 		}
 		if e.Err != nil {
-			l.logError("error encountered while applying options",
-				moduleField(e.ModuleName),
-				slog.Any("stacktrace", e.StackTrace),
-				slog.Any("moduletrace", e.ModuleTrace),
-				slogex.Error(e.Err))
+			l.logError(e.Err, "error encountered while applying options",
+				moduleField(f.Module, e.ModuleName),
+				l.traceField(f.StackTrace, e.StackTrace),
+				l.traceField(f.ModuleTrace, e.ModuleTrace),
+				f.errorField(e.Err))
 		}
 	case *fxevent.Replaced:
 		for _, rtype := range e.OutputTypeNames {
 			l.logEvent("replaced",
-				slog.Any("stacktrace", e.StackTrace),
-				slog.Any("moduletrace", e.ModuleTrace),
-				moduleField(e.ModuleName),
-				slog.String("type", rtype),
+				l.traceField(f.StackTrace, e.StackTrace),
+				l.traceField(f.ModuleTrace, e.ModuleTrace),
+				moduleField(f.Module, e.ModuleName),
+				slog.String(f.Type, l.name(rtype)),
 			)
 		}
 		if e.Err != nil {
-			l.logError("error encountered while replacing",
-				slog.Any("stacktrace", e.StackTrace),
-				slog.Any("moduletrace", e.ModuleTrace),
-				moduleField(e.ModuleName),
-				slogex.Error(e.Err))
+			l.logError(e.Err, "error encountered while replacing",
+				l.traceField(f.StackTrace, e.StackTrace),
+				l.traceField(f.ModuleTrace, e.ModuleTrace),
+				moduleField(f.Module, e.ModuleName),
+				f.errorField(e.Err))
 		}
 	case *fxevent.Decorated:
 		for _, rtype := range e.OutputTypeNames {
 			l.logEvent("decorated",
-				slog.String("decorator", e.DecoratorName),
-				slog.Any("stacktrace", e.StackTrace),
-				slog.Any("moduletrace", e.ModuleTrace),
-				moduleField(e.ModuleName),
-				slog.String("type", rtype),
+				slog.String(f.Decorator, l.name(e.DecoratorName)),
+				l.traceField(f.StackTrace, e.StackTrace),
+				l.traceField(f.ModuleTrace, e.ModuleTrace),
+				moduleField(f.Module, e.ModuleName),
+				slog.String(f.Type, l.name(rtype)),
 			)
 		}
 		if e.Err != nil {
-			l.logError("error encountered while applying options",
-				slog.Any("stacktrace", e.StackTrace),
-				slog.Any("moduletrace", e.ModuleTrace),
-				moduleField(e.ModuleName),
-				slogex.Error(e.Err))
+			l.logError(e.Err, "error encountered while applying options",
+				l.traceField(f.StackTrace, e.StackTrace),
+				l.traceField(f.ModuleTrace, e.ModuleTrace),
+				moduleField(f.Module, e.ModuleName),
+				f.errorField(e.Err))
 		}
 	case *fxevent.Run:
 		if e.Err != nil {
-			l.logError("error returned",
-				slog.String("name", e.Name),
-				slog.String("kind", e.Kind),
-				moduleField(e.ModuleName),
-				slogex.Error(e.Err),
+			l.logError(e.Err, "error returned",
+				slog.String(f.Name, l.name(e.Name)),
+				slog.String(f.Kind, e.Kind),
+				moduleField(f.Module, e.ModuleName),
+				f.errorField(e.Err),
 			)
 		} else {
 			l.logEvent("run",
-				slog.String("name", e.Name),
-				slog.String("kind", e.Kind),
-				moduleField(e.ModuleName),
+				slog.String(f.Name, l.name(e.Name)),
+				slog.String(f.Kind, e.Kind),
+				moduleField(f.Module, e.ModuleName),
 			)
 		}
 	case *fxevent.Invoking:
-		// Do not log stack as it will make logs hard to read.
+		// Do not log stack by default as it will make logs hard to read.
 		l.logEvent("invoking",
-			slog.String("function", e.FunctionName),
-			moduleField(e.ModuleName),
+			slog.String(f.Function, l.name(e.FunctionName)),
+			moduleField(f.Module, e.ModuleName),
+			l.invokingStackField(f.Stack),
 		)
 	case *fxevent.Invoked:
 		if e.Err != nil {
-			l.logError("invoke failed",
-				slogex.Error(e.Err),
-				slog.String("stack", e.Trace),
-				slog.String("function", e.FunctionName),
-				moduleField(e.ModuleName),
+			l.logError(e.Err, "invoke failed",
+				f.errorField(e.Err),
+				slog.String(f.Stack, e.Trace),
+				slog.String(f.Function, l.name(e.FunctionName)),
+				moduleField(f.Module, e.ModuleName),
 			)
 		}
 	case *fxevent.Stopping:
 		l.logEvent("received signal",
-			slog.String("signal", strings.ToUpper(e.Signal.String())))
+			slog.String(f.Signal, strings.ToUpper(e.Signal.String())))
 	case *fxevent.Stopped:
 		if e.Err != nil {
-			l.logError("stop failed", slogex.Error(e.Err))
+			l.logError(e.Err, "stop failed", f.errorField(e.Err))
 		}
 	case *fxevent.RollingBack:
-		l.logError("start failed, rolling back", slogex.Error(e.StartErr))
+		l.logError(e.StartErr, "start failed, rolling back", f.errorField(e.StartErr))
 	case *fxevent.RolledBack:
 		if e.Err != nil {
-			l.logError("rollback failed", slogex.Error(e.Err))
+			l.logError(e.Err, "rollback failed", f.errorField(e.Err))
 		}
 	case *fxevent.Started:
 		if e.Err != nil {
-			l.logError("start failed", slogex.Error(e.Err))
+			l.logError(e.Err, "start failed", f.errorField(e.Err))
 		} else {
 			l.logEvent("started")
 		}
 	case *fxevent.LoggerInitialized:
 		if e.Err != nil {
-			l.logError("custom logger initialization failed", slogex.Error(e.Err))
+			l.logError(e.Err, "custom logger initialization failed", f.errorField(e.Err))
 		} else {
-			l.logEvent("initialized custom fxevent.Logger", slog.String("function", e.ConstructorName))
+			l.logEvent("initialized custom fxevent.Logger", slog.String(f.Function, l.name(e.ConstructorName)))
 		}
+	default:
+		// Log events unknown to this logger, e.g. added in the newer Fx versions, so that they are not lost.
+		l.logger().Log(context.Background(), slog.LevelDebug, "unknown fx event",
+			l.withAttrs([]any{slog.String(f.Event, fmt.Sprintf("%T", event))})...)
+	}
+}
+
+// names returns the attribute keys set with UseFieldNames, or the defaults if it is not called.
+func (l *Logger) names() *FieldNames {
+	if l.fieldNames == nil {
+		return &defaultFieldNames
+	}
+	return l.fieldNames
+}
+
+func (l *Logger) name(name string) string {
+	if l.nameRedactor == nil {
+		return name
+	}
+	return l.nameRedactor(name)
+}
+
+func (l *Logger) invokingStackField(key string) slog.Attr {
+	if !l.invokingStack {
+		return slog.Attr{}
+	}
+	return slog.String(key, string(debug.Stack()))
+}
+
+func (l *Logger) traceField(key string, trace []string) slog.Attr {
+	if l.noTraces {
+		return slog.Attr{}
 	}
+	return slog.Any(key, trace)
 }
 
-func moduleField(name string) slog.Attr {
+func (n FieldNames) errorField(err error) slog.Attr {
+	if n.Error == "" || err == nil {
+		return slogex.Error(err)
+	}
+	return slog.Attr{Key: n.Error, Value: slogex.ErrorValue(err)}
+}
+
+func moduleField(key, name string) slog.Attr {
 	if len(name) == 0 {
 		return slog.Attr{}
 	}
-	return slog.String("module", name)
+	return slog.String(key, name)
 }
 
 func maybeBool(name string, b bool) slog.Attr {
@@ -221,3 +439,41 @@ func maybeBool(name string, b bool) slog.Attr {
 	}
 	return slog.Attr{}
 }
+
+// errorStats accumulates error events, it is safe for concurrent use. Nil errorStats ignores all the calls.
+type errorStats struct {
+	mu      sync.Mutex
+	n       int
+	lastErr error
+}
+
+func (s *errorStats) add(err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.n++
+	s.lastErr = err
+	s.mu.Unlock()
+}
+
+func (s *errorStats) count() int {
+	if s == nil {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.n
+}
+
+func (s *errorStats) last() error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastErr
+}
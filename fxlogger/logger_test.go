@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -458,3 +459,259 @@ func TestLogger(t *testing.T) {
 		}
 	})
 }
+
+func TestLoggerEventAttrs(t *testing.T) {
+	handler, observedLogs := observer.New(&observer.HandlerOptions{Level: slog.LevelDebug})
+	l := &Logger{Logger: slog.New(handler)}
+	l.UseEventAttrs(func(event fxevent.Event) []slog.Attr {
+		switch event.(type) {
+		case *fxevent.OnStartExecuting, *fxevent.OnStopExecuting:
+			return []slog.Attr{slog.String("category", "lifecycle")}
+		case *fxevent.Provided:
+			return []slog.Attr{slog.String("category", "graph")}
+		case *unknownEvent:
+			return []slog.Attr{slog.String("category", "unknown")}
+		}
+		return nil
+	})
+
+	l.LogEvent(&fxevent.OnStartExecuting{FunctionName: "hook.onStart", CallerName: "bytes.NewBuffer"})
+	l.LogEvent(&fxevent.Provided{ConstructorName: "bytes.NewBuffer()", OutputTypeNames: []string{"*bytes.Buffer"}})
+	l.LogEvent(&fxevent.Started{})
+	l.LogEvent(&unknownEvent{})
+
+	logs := observedLogs.TakeAll()
+	require.Len(t, logs, 4)
+
+	assert.Equal(t, "OnStart hook executing", logs[0].Record.Message)
+	assert.Equal(t, []slog.Attr{
+		slog.String("callee", "hook.onStart"),
+		slog.String("caller", "bytes.NewBuffer"),
+		slog.String("category", "lifecycle"),
+	}, logs[0].Attrs, "event attributes follow the event fields")
+
+	assert.Equal(t, "provided", logs[1].Record.Message)
+	assert.Equal(t, "graph", logs[1].AttrsMap()["category"])
+
+	assert.Equal(t, "started", logs[2].Record.Message)
+	assert.Equal(t, map[string]any{}, logs[2].AttrsMap())
+
+	assert.Equal(t, "unknown fx event", logs[3].Record.Message)
+	assert.Equal(t, map[string]any{"fx_event": "*fxlogger.unknownEvent", "category": "unknown"}, logs[3].AttrsMap())
+}
+
+func TestLoggerErrorTracking(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		handler, _ := observer.New(nil)
+		l := &Logger{Logger: slog.New(handler)}
+
+		l.LogEvent(&fxevent.Started{Err: errors.New("start error")})
+		assert.Equal(t, 0, l.ErrorCount())
+		assert.NoError(t, l.LastError())
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		handler, _ := observer.New(nil)
+		l := &Logger{Logger: slog.New(handler)}
+		l.UseErrorTracking()
+		l.UseEventAttrs(func(fxevent.Event) []slog.Attr {
+			return []slog.Attr{slog.String("source", "fx")}
+		})
+
+		l.LogEvent(&fxevent.Started{})
+		assert.Equal(t, 0, l.ErrorCount())
+		assert.NoError(t, l.LastError())
+
+		rollbackErr := errors.New("rollback error")
+		l.LogEvent(&fxevent.RollingBack{StartErr: errors.New("start error")})
+		l.LogEvent(&fxevent.RolledBack{Err: rollbackErr})
+		assert.Equal(t, 2, l.ErrorCount())
+		assert.Equal(t, rollbackErr, l.LastError())
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		handler, _ := observer.New(nil)
+		l := &Logger{Logger: slog.New(handler)}
+		l.UseErrorTracking()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					l.LogEvent(&fxevent.Stopped{Err: errors.New("stop error")})
+					_ = l.ErrorCount()
+					_ = l.LastError()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 400, l.ErrorCount())
+		assert.EqualError(t, l.LastError(), "stop error")
+	})
+}
+
+type unknownEvent struct {
+	fxevent.Event
+}
+
+func TestLoggerUnknownEvent(t *testing.T) {
+	handler, observedLogs := observer.New(&observer.HandlerOptions{Level: slog.LevelDebug})
+	l := &Logger{Logger: slog.New(handler)}
+
+	l.LogEvent(&unknownEvent{})
+
+	logs := observedLogs.TakeAll()
+	require.Len(t, logs, 1)
+	assert.Equal(t, slog.LevelDebug, logs[0].Record.Level)
+	assert.Equal(t, "unknown fx event", logs[0].Record.Message)
+	assert.Equal(t, map[string]any{"fx_event": "*fxlogger.unknownEvent"}, logs[0].AttrsMap())
+}
+
+func TestLoggerNameRedactor(t *testing.T) {
+	handler, observedLogs := observer.New(nil)
+	l := &Logger{Logger: slog.New(handler)}
+	l.UseNameRedactor(func(name string) string {
+		return strings.ReplaceAll(name, "internal/secret", "<redacted>")
+	})
+
+	l.LogEvent(&fxevent.OnStartExecuting{FunctionName: "internal/secret.onStart", CallerName: "internal/secret.New"})
+	l.LogEvent(&fxevent.Supplied{TypeName: "*internal/secret.Config"})
+	l.LogEvent(&fxevent.Provided{ConstructorName: "internal/secret.New()", OutputTypeNames: []string{"*internal/secret.Client"}})
+	l.LogEvent(&fxevent.Decorated{DecoratorName: "internal/secret.Wrap()", OutputTypeNames: []string{"*internal/secret.Client"}})
+	l.LogEvent(&fxevent.Invoking{FunctionName: "internal/secret.Run()", ModuleName: "internal/secret"})
+
+	logs := observedLogs.TakeAll()
+	require.Len(t, logs, 5)
+
+	assert.Equal(t, map[string]any{"callee": "<redacted>.onStart", "caller": "<redacted>.New"}, logs[0].AttrsMap())
+	assert.Equal(t, "*<redacted>.Config", logs[1].AttrsMap()["type"])
+	assert.Equal(t, "<redacted>.New()", logs[2].AttrsMap()["constructor"])
+	assert.Equal(t, "*<redacted>.Client", logs[2].AttrsMap()["type"])
+	assert.Equal(t, "<redacted>.Wrap()", logs[3].AttrsMap()["decorator"])
+	assert.Equal(t, "*<redacted>.Client", logs[3].AttrsMap()["type"])
+	// module names are not redacted
+	assert.Equal(t, map[string]any{"function": "<redacted>.Run()", "module": "internal/secret"}, logs[4].AttrsMap())
+}
+
+func TestLoggerFieldNames(t *testing.T) {
+	handler, observedLogs := observer.New(&observer.HandlerOptions{Level: slog.LevelDebug})
+	l := &Logger{Logger: slog.New(handler)}
+	l.UseFieldNames(FieldNames{Callee: "target", Caller: "component", Event: "event_type", Error: "err"})
+
+	l.LogEvent(&fxevent.OnStartExecuting{FunctionName: "hook", CallerName: "main"})
+	l.LogEvent(&fxevent.OnStartExecuted{FunctionName: "hook", CallerName: "main", Err: errors.New("some error")})
+	l.LogEvent(&fxevent.Invoking{FunctionName: "bytes.NewBuffer()", ModuleName: "foo"})
+	l.LogEvent(&unknownEvent{})
+
+	assert.Equal(t, []map[string]any{
+		{"target": "hook", "component": "main"},
+		{"target": "hook", "component": "main", "err": "some error"},
+		{"function": "bytes.NewBuffer()", "module": "foo"},
+		{"event_type": "*fxlogger.unknownEvent"},
+	}, observer.AttrsMaps(observedLogs), "not overridden names must fall back to the defaults")
+
+	t.Run("defaults", func(t *testing.T) {
+		handler, observedLogs := observer.New(&observer.HandlerOptions{Level: slog.LevelDebug})
+		l := &Logger{Logger: slog.New(handler)}
+		l.UseFieldNames(FieldNames{})
+
+		l.LogEvent(&fxevent.OnStartExecuted{FunctionName: "hook", CallerName: "main", Err: errors.New("some error")})
+		l.LogEvent(&unknownEvent{})
+		assert.Equal(t, []map[string]any{
+			{"callee": "hook", "caller": "main", "error": "some error"},
+			{"fx_event": "*fxlogger.unknownEvent"},
+		}, observer.AttrsMaps(observedLogs))
+	})
+}
+
+func TestLoggerTraces(t *testing.T) {
+	handler, observedLogs := observer.New(nil)
+	l := &Logger{Logger: slog.New(handler)}
+	l.UseTraces(false)
+
+	stackTrace := []string{"main.main()"}
+	moduleTrace := []string{"foo"}
+	l.LogEvent(&fxevent.Supplied{TypeName: "*bytes.Buffer", StackTrace: stackTrace, ModuleTrace: moduleTrace})
+	l.LogEvent(&fxevent.Provided{
+		ConstructorName: "bytes.NewBuffer()",
+		OutputTypeNames: []string{"*bytes.Buffer"},
+		StackTrace:      stackTrace,
+		ModuleTrace:     moduleTrace,
+		Err:             errors.New("some error"),
+	})
+
+	assert.Equal(t, []map[string]any{
+		{"type": "*bytes.Buffer"},
+		{"constructor": "bytes.NewBuffer()", "type": "*bytes.Buffer"},
+		{"error": "some error"},
+	}, observer.AttrsMaps(observedLogs))
+
+	observer.Reset(observedLogs)
+	l.UseTraces(true)
+	l.LogEvent(&fxevent.Replaced{OutputTypeNames: []string{"*bytes.Buffer"}, StackTrace: stackTrace, ModuleTrace: moduleTrace})
+	assert.Equal(t, []map[string]any{
+		{"type": "*bytes.Buffer", "stacktrace": stackTrace, "moduletrace": moduleTrace},
+	}, observer.AttrsMaps(observedLogs))
+}
+
+func TestLoggerInvokingStack(t *testing.T) {
+	handler, observedLogs := observer.New(nil)
+	l := &Logger{Logger: slog.New(handler)}
+
+	l.LogEvent(&fxevent.Invoking{FunctionName: "bytes.NewBuffer()"})
+	l.UseInvokingStack(true)
+	l.LogEvent(&fxevent.Invoking{FunctionName: "bytes.NewBuffer()"})
+
+	logs := observedLogs.TakeAll()
+	require.Len(t, logs, 2)
+
+	assert.Equal(t, map[string]any{"function": "bytes.NewBuffer()"}, logs[0].AttrsMap())
+
+	attrs := logs[1].AttrsMap()
+	assert.Equal(t, "bytes.NewBuffer()", attrs["function"])
+	assert.Contains(t, attrs["stack"], "TestLoggerInvokingStack", "stack of the logging goroutine is expected")
+}
+
+func TestLoggerNilLogger(t *testing.T) {
+	logs := observer.Capture(func() {
+		l := &Logger{}
+		l.UseEventAttrs(func(fxevent.Event) []slog.Attr {
+			return []slog.Attr{slog.String("source", "fx")}
+		})
+
+		require.NotPanics(t, func() {
+			l.LogEvent(&fxevent.Started{})
+			l.LogEvent(&fxevent.Started{Err: errors.New("some error")})
+		})
+	})
+
+	assert.Equal(t, []string{"started", "start failed"}, observer.Messages(logs), "slog.Default is expected to be used")
+	assert.Equal(t, []map[string]any{
+		{"source": "fx"},
+		{"source": "fx", "error": "some error"},
+	}, observer.AttrsMaps(logs))
+}
+
+func TestLoggerSingleLevel(t *testing.T) {
+	handler, observedLogs := observer.New(&observer.HandlerOptions{Level: slog.LevelDebug})
+	l := &Logger{Logger: slog.New(handler)}
+	l.UseSingleLevel(slog.LevelDebug)
+
+	l.LogEvent(&fxevent.Started{})
+	l.LogEvent(&fxevent.Started{Err: errors.New("some error")})
+
+	assert.Equal(t, map[slog.Level]int{slog.LevelDebug: 2}, observer.CountByLevel(observedLogs))
+	observer.Reset(observedLogs)
+
+	t.Run("UseErrorLevel after", func(t *testing.T) {
+		l.UseErrorLevel(slog.LevelWarn)
+
+		l.LogEvent(&fxevent.Started{})
+		l.LogEvent(&fxevent.Started{Err: errors.New("some error")})
+
+		assert.Equal(t, map[slog.Level]int{slog.LevelDebug: 1, slog.LevelWarn: 1}, observer.CountByLevel(observedLogs))
+	})
+}
@@ -0,0 +1,258 @@
+// Package assertlog provides testify-friendly assertions for the logs collected by
+// github.com/vgarvardt/slogex/observer.
+//
+// All the assertions report the failure with testing.TB.Errorf, dump observed records (level, message and
+// attributes) to the failure output and return whether the assertion succeeded. Assertions do not consume
+// observed records, so they can be combined and followed by other checks.
+package assertlog
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vgarvardt/slogex/observer"
+)
+
+// AssertMessageLogged asserts that there is at least one observed log with the specified message.
+func AssertMessageLogged(t testing.TB, logs observer.ObservedLogs, msg string) bool {
+	t.Helper()
+
+	snapshot := observer.Snapshot(logs)
+	if observer.Contains(snapshot, msg) {
+		return true
+	}
+
+	t.Errorf("Expected message %q to be logged, observed logs:\n%s", msg, dump(snapshot))
+	return false
+}
+
+// AssertNoLevel asserts that there are no observed logs logged at exactly the specified level.
+func AssertNoLevel(t testing.TB, logs observer.ObservedLogs, level slog.Level) bool {
+	t.Helper()
+
+	snapshot := observer.Snapshot(logs)
+	if observer.CountLevel(snapshot, level) == 0 {
+		return true
+	}
+
+	t.Errorf("Expected no logs at level %s, observed logs:\n%s", level, dump(snapshot))
+	return false
+}
+
+// AssertAttr asserts that there is at least one observed log with the specified message and attribute.
+// Attributes are compared the same way as observer.ObservedLogs.FilterAttr does.
+func AssertAttr(t testing.TB, logs observer.ObservedLogs, msg string, attr slog.Attr) bool {
+	t.Helper()
+
+	snapshot := observer.Snapshot(logs)
+	if observer.ContainsAttr(snapshot.FilterMessage(msg), attr) {
+		return true
+	}
+
+	t.Errorf("Expected message %q to be logged with attribute %s, observed logs:\n%s", msg, attr, dump(snapshot))
+	return false
+}
+
+// AssertLoggedTimes asserts that the specified message is observed exactly n times.
+func AssertLoggedTimes(t testing.TB, logs observer.ObservedLogs, msg string, n int) bool {
+	t.Helper()
+
+	snapshot := observer.Snapshot(logs)
+	got := observer.CountMessage(snapshot, msg)
+	if got == n {
+		return true
+	}
+
+	t.Errorf("Expected message %q to be logged %d times, but got %d, observed logs:\n%s", msg, n, got, dump(snapshot))
+	return false
+}
+
+// AssertSequence asserts that the specified messages are observed in the specified relative order,
+// other logs may be observed in between.
+func AssertSequence(t testing.TB, logs observer.ObservedLogs, msgs ...string) bool {
+	t.Helper()
+
+	snapshot := observer.Snapshot(logs)
+	matched := 0
+	for _, m := range observer.Messages(snapshot) {
+		if matched < len(msgs) && m == msgs[matched] {
+			matched++
+		}
+	}
+	if matched == len(msgs) {
+		return true
+	}
+
+	t.Errorf("Expected messages %q to be logged in order, but %q is missing after %q, observed logs:\n%s",
+		msgs, msgs[matched], msgs[:matched], dump(snapshot))
+	return false
+}
+
+// AssertSequenceStrict asserts that the specified messages are observed in the specified order
+// as consecutive logs, without other logs in between.
+func AssertSequenceStrict(t testing.TB, logs observer.ObservedLogs, msgs ...string) bool {
+	t.Helper()
+
+	snapshot := observer.Snapshot(logs)
+	got := observer.Messages(snapshot)
+	for i := 0; i <= len(got)-len(msgs); i++ {
+		if slices.Equal(got[i:i+len(msgs)], msgs) {
+			return true
+		}
+	}
+
+	t.Errorf("Expected messages %q to be logged consecutively in order, observed logs:\n%s", msgs, dump(snapshot))
+	return false
+}
+
+// AssertNoLogsSince asserts that no logs are observed after the cursor position, e.g. to catch goroutines logging
+// after the component under test is stopped. Take the cursor with observer.CursorReader.Cursor at the point after
+// which no logs are expected. Logs that were observed after the cursor, but are not available anymore,
+// e.g. evicted because of the MaxLogs limit, fail the assertion as well.
+func AssertNoLogsSince(t testing.TB, logs observer.ObservedLogs, cursor observer.Cursor) bool {
+	t.Helper()
+
+	cr, ok := logs.(observer.CursorReader)
+	if !ok {
+		t.Errorf("Observed logs do not support cursors, observer.CursorReader is not implemented")
+		return false
+	}
+
+	records, _, dropped := cr.AllSince(cursor)
+	if len(records) == 0 && dropped == 0 {
+		return true
+	}
+
+	t.Errorf("Expected no logs since the cursor, but got %d (%d not available anymore), offending logs:\n%s",
+		len(records)+dropped, dropped, dumpRecords(records))
+	return false
+}
+
+// AssertRecords asserts that the observed logs match the wanted records by level, message and attributes,
+// the differences are reported as observer.Diff renders them. Record time is ignored unless
+// observer.DiffCompareTime option is set.
+func AssertRecords(t testing.TB, logs observer.ObservedLogs, want []observer.LoggedRecord, opts ...observer.DiffOption) bool {
+	t.Helper()
+
+	diff := observer.Diff(want, logs.All(), opts...)
+	if diff == "" {
+		return true
+	}
+
+	t.Errorf("Observed logs do not match wanted records:\n%s", diff)
+	return false
+}
+
+// RequireExactMessages asserts that the observed logs messages match the wanted ones exactly and in the same order.
+// In case of mismatch test is failed immediately with testing.TB.Fatalf and the side-by-side comparison
+// of messages is reported.
+func RequireExactMessages(t testing.TB, logs observer.ObservedLogs, want ...string) {
+	t.Helper()
+
+	got := observer.Messages(logs)
+	if slices.Equal(want, got) {
+		return
+	}
+
+	t.Fatalf("Observed logs messages do not match:\n%s", sideBySide(want, got))
+}
+
+// Eventually polls the observed logs every interval until cond returns true or the timeout elapses,
+// in which case the test is failed and all the observed logs are reported. Unlike observer.WaitFor
+// it does not rely on the collection notifications, so it works with any ObservedLogs implementation.
+// Polling happens in the calling goroutine, so nothing is left running after it returns.
+func Eventually(t testing.TB, logs observer.ObservedLogs, timeout, interval time.Duration, cond func(observer.ObservedLogs) bool) bool {
+	t.Helper()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if cond(logs) {
+			return true
+		}
+
+		select {
+		case <-timer.C:
+			if cond(logs) {
+				return true
+			}
+
+			var sb strings.Builder
+			if err := observer.Dump(&sb, logs); err != nil {
+				fmt.Fprintf(&sb, "failed to dump observed logs: %v\n", err)
+			}
+			t.Errorf("Condition is not satisfied within %s, observed logs (%d):\n%s", timeout, logs.Len(), sb.String())
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// AssertExpectations asserts that all the expectations are met, see observer.Expectations.Verify.
+func AssertExpectations(t testing.TB, exp *observer.Expectations) bool {
+	t.Helper()
+
+	if err := exp.Verify(); err != nil {
+		t.Errorf("Observed logs do not satisfy expectations, %v", err)
+		return false
+	}
+	return true
+}
+
+// AssertExpectationsOnCleanup registers AssertExpectations to be called when the test is finished.
+func AssertExpectationsOnCleanup(t testing.TB, exp *observer.Expectations) {
+	t.Cleanup(func() {
+		AssertExpectations(t, exp)
+	})
+}
+
+// dump renders observed records one per line with their position, level, message and attributes.
+func dump(logs observer.ObservedLogs) string {
+	return dumpRecords(logs.All())
+}
+
+func dumpRecords(records []observer.LoggedRecord) string {
+	if len(records) == 0 {
+		return "  <none>\n"
+	}
+
+	var sb strings.Builder
+	for i, r := range records {
+		fmt.Fprintf(&sb, "  %-4d %-5s %q %v\n", i, r.Record.Level, r.Record.Message, r.AttrsMap())
+	}
+	return sb.String()
+}
+
+// sideBySide renders wanted and got messages as a table with the mismatching lines marked.
+func sideBySide(want, got []string) string {
+	width := len("want")
+	for _, w := range want {
+		width = max(width, len(w))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "  %-4s %-*s | %s\n", "#", width, "want", "got")
+	for i := 0; i < max(len(want), len(got)); i++ {
+		w, g := "<none>", "<none>"
+		if i < len(want) {
+			w = want[i]
+		}
+		if i < len(got) {
+			g = got[i]
+		}
+
+		marker := " "
+		if i >= len(want) || i >= len(got) || want[i] != got[i] {
+			marker = "!"
+		}
+		fmt.Fprintf(&sb, "%s %-4d %-*s | %s\n", marker, i, width, w, g)
+	}
+	return sb.String()
+}
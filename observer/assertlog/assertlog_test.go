@@ -0,0 +1,361 @@
+package assertlog
+
+import (
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vgarvardt/slogex/observer"
+)
+
+// fakeTB is a testing.TB implementation that records failures instead of failing the test.
+type fakeTB struct {
+	testing.TB
+
+	failed   bool
+	msg      string
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.failed = true
+	f.msg = fmt.Sprintf(format, args...)
+}
+
+func (f *fakeTB) Fatalf(format string, args ...any) {
+	f.Errorf(format, args...)
+}
+
+func (f *fakeTB) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+// cleanup runs registered cleanup functions in the reverse order, the same way testing package does.
+func (f *fakeTB) cleanup() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func newLogs() observer.ObservedLogs {
+	handler, logs := observer.New(nil)
+	logger := slog.New(handler)
+
+	logger.Info("retry", slog.Int("attempt", 1))
+	logger.Info("retry", slog.Int("attempt", 2))
+	logger.Warn("giving up", slog.Group("http", slog.Int("status", 503)))
+	return logs
+}
+
+func TestAssertMessageLogged(t *testing.T) {
+	logs := newLogs()
+
+	ft := &fakeTB{TB: t}
+	assert.True(t, AssertMessageLogged(ft, logs, "retry"))
+	assert.False(t, ft.failed)
+
+	assert.False(t, AssertMessageLogged(ft, logs, "done"))
+	assert.True(t, ft.failed)
+	assert.Equal(t, `Expected message "done" to be logged, observed logs:
+  0    INFO  "retry" map[attempt:1]
+  1    INFO  "retry" map[attempt:2]
+  2    WARN  "giving up" map[http:map[status:503]]
+`, ft.msg)
+
+	assert.Equal(t, 3, logs.Len(), "assertions must not consume observed logs")
+}
+
+func TestAssertNoLevel(t *testing.T) {
+	logs := newLogs()
+
+	ft := &fakeTB{TB: t}
+	assert.True(t, AssertNoLevel(ft, logs, slog.LevelError))
+	assert.False(t, ft.failed)
+
+	assert.False(t, AssertNoLevel(ft, logs, slog.LevelWarn))
+	assert.True(t, ft.failed)
+	assert.Contains(t, ft.msg, "Expected no logs at level WARN")
+}
+
+func TestAssertAttr(t *testing.T) {
+	logs := newLogs()
+
+	ft := &fakeTB{TB: t}
+	assert.True(t, AssertAttr(ft, logs, "retry", slog.Int("attempt", 2)))
+	assert.True(t, AssertAttr(ft, logs, "giving up", slog.Int("status", 503)))
+	assert.False(t, ft.failed)
+
+	assert.False(t, AssertAttr(ft, logs, "giving up", slog.Int("attempt", 2)))
+	assert.True(t, ft.failed)
+	assert.Contains(t, ft.msg, `Expected message "giving up" to be logged with attribute attempt=2`)
+}
+
+func TestAssertLoggedTimes(t *testing.T) {
+	logs := newLogs()
+
+	ft := &fakeTB{TB: t}
+	assert.True(t, AssertLoggedTimes(ft, logs, "retry", 2))
+	assert.True(t, AssertLoggedTimes(ft, logs, "done", 0))
+	assert.False(t, ft.failed)
+
+	assert.False(t, AssertLoggedTimes(ft, logs, "retry", 3))
+	assert.True(t, ft.failed)
+	assert.Contains(t, ft.msg, `Expected message "retry" to be logged 3 times, but got 2`)
+}
+
+func newSequenceLogs() observer.ObservedLogs {
+	handler, logs := observer.New(nil)
+	logger := slog.New(handler)
+
+	logger.Info("connect")
+	logger.Info("retry")
+	logger.Info("authenticate")
+	logger.Info("ready")
+	return logs
+}
+
+func TestAssertSequence(t *testing.T) {
+	logs := newSequenceLogs()
+
+	ft := &fakeTB{TB: t}
+	assert.True(t, AssertSequence(ft, logs, "connect", "authenticate", "ready"))
+	assert.True(t, AssertSequence(ft, logs, "retry", "ready"))
+	assert.True(t, AssertSequence(ft, logs))
+	assert.False(t, ft.failed)
+
+	assert.False(t, AssertSequence(ft, logs, "connect", "ready", "authenticate"))
+	assert.True(t, ft.failed)
+	assert.Equal(t, `Expected messages ["connect" "ready" "authenticate"] to be logged in order, but "authenticate" is missing after ["connect" "ready"], observed logs:
+  0    INFO  "connect" map[]
+  1    INFO  "retry" map[]
+  2    INFO  "authenticate" map[]
+  3    INFO  "ready" map[]
+`, ft.msg)
+
+	ft = &fakeTB{TB: t}
+	assert.False(t, AssertSequence(ft, logs, "connect", "connect"))
+	assert.True(t, ft.failed)
+}
+
+func TestAssertSequenceStrict(t *testing.T) {
+	logs := newSequenceLogs()
+
+	ft := &fakeTB{TB: t}
+	assert.True(t, AssertSequenceStrict(ft, logs, "authenticate", "ready"))
+	assert.True(t, AssertSequenceStrict(ft, logs, "connect", "retry", "authenticate", "ready"))
+	assert.True(t, AssertSequenceStrict(ft, logs))
+	assert.False(t, ft.failed)
+
+	assert.False(t, AssertSequenceStrict(ft, logs, "connect", "authenticate", "ready"))
+	assert.True(t, ft.failed)
+	assert.Contains(t, ft.msg, `Expected messages ["connect" "authenticate" "ready"] to be logged consecutively in order`)
+	assert.Contains(t, ft.msg, `1    INFO  "retry" map[]`)
+
+	ft = &fakeTB{TB: t}
+	assert.False(t, AssertSequenceStrict(ft, logs, "ready", "authenticate"))
+	assert.True(t, ft.failed)
+}
+
+func TestDumpEmpty(t *testing.T) {
+	_, logs := observer.New(nil)
+
+	ft := &fakeTB{TB: t}
+	assert.False(t, AssertMessageLogged(ft, logs, "retry"))
+	assert.Equal(t, "Expected message \"retry\" to be logged, observed logs:\n  <none>\n", ft.msg)
+}
+
+func TestAssertNoLogsSince(t *testing.T) {
+	handler, logs := observer.New(nil)
+	logger := slog.New(handler)
+
+	logger.Info("starting")
+
+	// worker logs until it is stopped, but one more time after that
+	stop, stopped, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Info("working")
+		<-stop
+		close(stopped)
+		<-stop
+		logger.Warn("late", slog.Int("i", 1))
+	}()
+
+	stop <- struct{}{}
+	<-stopped
+	cursor := logs.(observer.CursorReader).Cursor()
+
+	ft := &fakeTB{TB: t}
+	assert.True(t, AssertNoLogsSince(ft, logs, cursor))
+	assert.False(t, ft.failed)
+
+	close(stop)
+	<-done
+
+	assert.False(t, AssertNoLogsSince(ft, logs, cursor))
+	assert.True(t, ft.failed)
+	assert.Equal(t, `Expected no logs since the cursor, but got 1 (0 not available anymore), offending logs:
+  0    WARN  "late" map[i:1]
+`, ft.msg)
+}
+
+func TestAssertNoLogsSinceDropped(t *testing.T) {
+	handler, logs := observer.New(&observer.HandlerOptions{MaxLogs: 2})
+	logger := slog.New(handler)
+
+	cursor := logs.(observer.CursorReader).Cursor()
+	for i := 0; i < 3; i++ {
+		logger.Info("late", slog.Int("i", i))
+	}
+
+	ft := &fakeTB{TB: t}
+	assert.False(t, AssertNoLogsSince(ft, logs, cursor))
+	assert.Contains(t, ft.msg, "but got 3 (1 not available anymore)")
+}
+
+func TestAssertRecords(t *testing.T) {
+	logs := newLogs()
+	want := []observer.LoggedRecord{
+		{Record: slog.NewRecord(time.Time{}, slog.LevelInfo, "retry", 0), Attrs: []slog.Attr{slog.Int("attempt", 1)}},
+		{Record: slog.NewRecord(time.Time{}, slog.LevelInfo, "retry", 0), Attrs: []slog.Attr{slog.Int("attempt", 2)}},
+		{Record: slog.NewRecord(time.Time{}, slog.LevelWarn, "giving up", 0), Attrs: []slog.Attr{slog.Group("http", slog.Int("status", 503))}},
+	}
+
+	ft := &fakeTB{TB: t}
+	assert.True(t, AssertRecords(ft, logs, want))
+	assert.False(t, ft.failed)
+
+	want[1].Attrs = []slog.Attr{slog.Int("attempt", 3)}
+	assert.False(t, AssertRecords(ft, logs, want))
+	assert.True(t, ft.failed)
+	assert.Equal(t, `Observed logs do not match wanted records:
+record 1:
+  attr "attempt": want 3 (int64), got 2 (int64)
+`, ft.msg)
+
+	ft = &fakeTB{TB: t}
+	assert.True(t, AssertRecords(ft, logs, want, observer.DiffIgnoreKeys("attempt")))
+	assert.False(t, ft.failed)
+}
+
+func TestRequireExactMessages(t *testing.T) {
+	handler, logs := observer.New(nil)
+	logger := slog.New(handler)
+
+	logger.Info("starting")
+	logger.Info("processing")
+	logger.Info("done")
+
+	t.Run("match", func(t *testing.T) {
+		ft := &fakeTB{TB: t}
+		RequireExactMessages(ft, logs, "starting", "processing", "done")
+		assert.False(t, ft.failed)
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		ft := &fakeTB{TB: t}
+		RequireExactMessages(ft, logs, "starting", "done")
+		assert.True(t, ft.failed)
+		assert.Equal(t, `Observed logs messages do not match:
+  #    want     | got
+  0    starting | starting
+! 1    done     | processing
+! 2    <none>   | done
+`, ft.msg)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		ft := &fakeTB{TB: t}
+		RequireExactMessages(ft, logs.FilterMessage("nope"))
+		assert.False(t, ft.failed)
+
+		RequireExactMessages(ft, logs.FilterMessage("nope"), "starting")
+		assert.True(t, ft.failed)
+	})
+}
+
+func TestEventually(t *testing.T) {
+	handler, logs := observer.New(nil)
+	logger := slog.New(handler)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			time.Sleep(10 * time.Millisecond)
+			logger.Info("tick", slog.Int("i", i))
+		}
+	}()
+
+	t.Run("satisfied", func(t *testing.T) {
+		ft := &fakeTB{TB: t}
+		assert.True(t, Eventually(ft, logs, time.Second, time.Millisecond, func(logs observer.ObservedLogs) bool {
+			return observer.CountMessage(logs, "tick") == 3
+		}))
+		assert.False(t, ft.failed)
+	})
+	<-done
+
+	t.Run("timeout", func(t *testing.T) {
+		ft := &fakeTB{TB: t}
+		assert.False(t, Eventually(ft, logs, 20*time.Millisecond, time.Millisecond, func(logs observer.ObservedLogs) bool {
+			return observer.Contains(logs, "done")
+		}))
+		assert.True(t, ft.failed)
+		assert.Contains(t, ft.msg, "Condition is not satisfied within 20ms, observed logs (3):\n")
+		assert.Contains(t, ft.msg, "level=INFO msg=tick i=2\n")
+	})
+}
+
+func TestAssertExpectations(t *testing.T) {
+	logs := newLogs()
+
+	t.Run("met", func(t *testing.T) {
+		exp := observer.Expect(logs)
+		exp.Message("retry").Times(2)
+		exp.Level(slog.LevelWarn).Times(1)
+
+		ft := &fakeTB{TB: t}
+		assert.True(t, AssertExpectations(ft, exp))
+		assert.False(t, ft.failed)
+	})
+
+	t.Run("not met", func(t *testing.T) {
+		exp := observer.Expect(logs)
+		exp.Message("retry").Times(1)
+
+		ft := &fakeTB{TB: t}
+		assert.False(t, AssertExpectations(ft, exp))
+		assert.True(t, ft.failed)
+		assert.Equal(t, `Observed logs do not satisfy expectations, log expectations are not met:
+  message "retry": expected exactly 1, observed 2
+observed logs (3):
+  INFO  "retry" map[attempt:1]
+  INFO  "retry" map[attempt:2]
+  WARN  "giving up" map[http:map[status:503]]
+`, ft.msg)
+	})
+
+	t.Run("cleanup", func(t *testing.T) {
+		ft := &fakeTB{TB: t}
+		exp := observer.Expect(logs)
+		exp.Message("disconnected")
+		AssertExpectationsOnCleanup(ft, exp)
+		assert.False(t, ft.failed)
+
+		ft.cleanup()
+		assert.True(t, ft.failed)
+		assert.Contains(t, ft.msg, `message "disconnected": expected exactly 1, observed 0`)
+	})
+}
+
+func TestAssertNoLogsSinceNotCursorReader(t *testing.T) {
+	ft := &fakeTB{TB: t}
+	assert.False(t, AssertNoLogsSince(ft, struct{ observer.ObservedLogs }{observer.NewObservedLogsDefault(0)}, observer.Cursor{}))
+	assert.Equal(t, "Observed logs do not support cursors, observer.CursorReader is not implemented", ft.msg)
+}
@@ -0,0 +1,42 @@
+package assertlog_test
+
+import (
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/vgarvardt/slogex/observer"
+	"github.com/vgarvardt/slogex/observer/assertlog"
+)
+
+// exampleT stands in for the *testing.T the test function receives, it prints failures instead of failing.
+type exampleT struct {
+	testing.TB
+}
+
+func (exampleT) Helper() {}
+
+func (exampleT) Errorf(format string, args ...any) {
+	fmt.Printf(format+"\n", args...)
+}
+
+func retry(logger *slog.Logger, attempts int) {
+	for i := 1; i <= attempts; i++ {
+		logger.Info("retry", slog.Int("attempt", i))
+	}
+	logger.Warn("giving up", slog.Int("attempts", attempts))
+}
+
+func Example() {
+	// t is the *testing.T of the test function
+	t := exampleT{}
+
+	handler, logs := observer.New(nil)
+	retry(slog.New(handler), 3)
+
+	assertlog.AssertLoggedTimes(t, logs, "retry", 3)
+	assertlog.AssertAttr(t, logs, "giving up", slog.Int("attempts", 3))
+	assertlog.AssertMessageLogged(t, logs, "giving up")
+	assertlog.AssertNoLevel(t, logs, slog.LevelError)
+	// Output:
+}
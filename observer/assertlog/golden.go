@@ -0,0 +1,116 @@
+package assertlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vgarvardt/slogex/observer"
+)
+
+// GoldenUpdateEnv is the environment variable that makes Golden rewrite the golden files when set to non-empty value.
+const GoldenUpdateEnv = "SLOGEX_UPDATE_GOLDEN"
+
+// Golden compares the observed logs with the golden file at path and fails the test with the unified diff
+// of the golden file and the observed logs if they differ. Logs are serialized without time, see ObservedLogs.AllUntimed,
+// one JSON object per line as LoggedRecord.MarshalJSON renders it, so attribute keys are sorted and levels
+// have canonical names. The golden file is rewritten with the observed logs when update is true
+// or GoldenUpdateEnv environment variable is set.
+func Golden(t testing.TB, logs observer.ObservedLogs, path string, update bool) bool {
+	t.Helper()
+
+	got, err := goldenLines(logs)
+	if err != nil {
+		t.Errorf("Failed to serialize observed logs: %v", err)
+		return false
+	}
+
+	if update || os.Getenv(GoldenUpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Errorf("Failed to create golden file directory: %v", err)
+			return false
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Errorf("Failed to write golden file: %v", err)
+			return false
+		}
+		return true
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("Failed to read golden file, set %s=1 to create it: %v", GoldenUpdateEnv, err)
+		return false
+	}
+	if bytes.Equal(want, got) {
+		return true
+	}
+
+	t.Errorf("Observed logs do not match golden file %s:\n%s", path, unifiedDiff(path, "observed", string(want), string(got)))
+	return false
+}
+
+// goldenLines serializes observed logs without time, one JSON object per line.
+func goldenLines(logs observer.ObservedLogs) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, r := range logs.AllUntimed() {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// unifiedDiff renders the line diff of a and b in the unified format as a single hunk with the full context.
+func unifiedDiff(aName, bName, a, b string) string {
+	aLines, bLines := splitLines(a), splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of aLines[i:] and bLines[j:]
+	lcs := make([][]int, len(aLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bLines)+1)
+	}
+	for i := len(aLines) - 1; i >= 0; i-- {
+		for j := len(bLines) - 1; j >= 0; j-- {
+			if aLines[i] == bLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n@@ -1,%d +1,%d @@\n", aName, bName, len(aLines), len(bLines))
+	i, j := 0, 0
+	for i < len(aLines) || j < len(bLines) {
+		switch {
+		case i < len(aLines) && j < len(bLines) && aLines[i] == bLines[j]:
+			sb.WriteString(" " + aLines[i] + "\n")
+			i++
+			j++
+		case i < len(aLines) && (j == len(bLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("-" + aLines[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+" + bLines[j] + "\n")
+			j++
+		}
+	}
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
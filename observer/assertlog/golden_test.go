@@ -0,0 +1,77 @@
+package assertlog
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vgarvardt/slogex/observer"
+)
+
+func TestGolden(t *testing.T) {
+	t.Setenv(GoldenUpdateEnv, "")
+
+	handler, logs := observer.New(nil)
+	logger := slog.New(handler).With(slog.String("component", "db"))
+
+	logger.Info("connecting", slog.String("dsn", "postgres://localhost"))
+	logger.WithGroup("query").Warn("slow", slog.Group("stats", slog.Int("rows", 10), slog.Group("timing", slog.Int("ms", 1500))))
+
+	path := filepath.Join(t.TempDir(), "testdata", "golden.jsonl")
+
+	t.Run("missing", func(t *testing.T) {
+		ft := &fakeTB{TB: t}
+		assert.False(t, Golden(ft, logs, path, false))
+		assert.Contains(t, ft.msg, "Failed to read golden file, set SLOGEX_UPDATE_GOLDEN=1 to create it")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		ft := &fakeTB{TB: t}
+		assert.True(t, Golden(ft, logs, path, true))
+		assert.False(t, ft.failed)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, `{"level":"INFO","msg":"connecting","attrs":{"component":"db","dsn":"postgres://localhost"}}
+{"level":"WARN","msg":"slow","attrs":{"component":"db","query":{"stats":{"rows":10,"timing":{"ms":1500}}}}}
+`, string(data))
+	})
+
+	t.Run("match", func(t *testing.T) {
+		ft := &fakeTB{TB: t}
+		assert.True(t, Golden(ft, logs, path, false))
+		assert.False(t, ft.failed)
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		changed := logs.FilterMessage("slow")
+		changed.Add(slog.NewRecord(time.Now(), slog.LevelInfo, "done", 0), nil)
+
+		ft := &fakeTB{TB: t}
+		assert.False(t, Golden(ft, changed, path, false))
+		assert.Equal(t, "Observed logs do not match golden file "+path+`:
+--- `+path+`
++++ observed
+@@ -1,2 +1,2 @@
+-{"level":"INFO","msg":"connecting","attrs":{"component":"db","dsn":"postgres://localhost"}}
+ {"level":"WARN","msg":"slow","attrs":{"component":"db","query":{"stats":{"rows":10,"timing":{"ms":1500}}}}}
++{"level":"INFO","msg":"done","attrs":{}}
+`, ft.msg)
+	})
+
+	t.Run("update env", func(t *testing.T) {
+		t.Setenv(GoldenUpdateEnv, "1")
+
+		ft := &fakeTB{TB: t}
+		assert.True(t, Golden(ft, logs.FilterMessage("connecting"), path, false))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, `{"level":"INFO","msg":"connecting","attrs":{"component":"db","dsn":"postgres://localhost"}}`+"\n", string(data))
+	})
+}
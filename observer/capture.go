@@ -0,0 +1,48 @@
+package observer
+
+import (
+	"log"
+	"log/slog"
+	"sync"
+)
+
+// captureMu serializes captures, as they replace the process-wide default logger.
+var captureMu sync.Mutex
+
+// Capture replaces slog.Default with the logger backed by a new observer handler, runs fn and returns
+// the logs observed while it was running, including the ones written with the log package as slog.SetDefault
+// redirects it as well. The previous default logger and log package output are restored when fn returns
+// or panics, in the latter case the panic is propagated.
+//
+// Captures are serialized with a package-level mutex, but the default logger is shared by the whole process,
+// so Capture is not safe to use in parallel tests: logs of the other tests may be captured as well.
+func Capture(fn func()) ObservedLogs {
+	handler, logs := New(nil)
+	capture(handler, fn)
+	return logs
+}
+
+// CaptureT is the same as Capture, but the observer handler is created with NewForTesting,
+// so the observed logs are dumped when the test fails.
+func CaptureT(tb TB, fn func()) ObservedLogs {
+	tb.Helper()
+
+	handler, logs := NewForTesting(tb, nil)
+	capture(handler, fn)
+	return logs
+}
+
+func capture(handler slog.Handler, fn func()) {
+	captureMu.Lock()
+	defer captureMu.Unlock()
+
+	prev, prevOutput, prevFlags := slog.Default(), log.Writer(), log.Flags()
+	defer func() {
+		slog.SetDefault(prev)
+		log.SetOutput(prevOutput)
+		log.SetFlags(prevFlags)
+	}()
+
+	slog.SetDefault(slog.New(handler))
+	fn()
+}
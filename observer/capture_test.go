@@ -0,0 +1,59 @@
+package observer
+
+import (
+	"log"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapture(t *testing.T) {
+	prev, prevOutput, prevFlags := slog.Default(), log.Writer(), log.Flags()
+
+	logs := Capture(func() {
+		slog.Info("captured", slog.Int("i", 1))
+		slog.Debug("below level")
+		log.Print("from log package")
+	})
+
+	assert.Equal(t, []string{"captured", "from log package"}, Messages(logs))
+	assert.Equal(t, []map[string]any{{"i": int64(1)}, {}}, AttrsMaps(logs))
+
+	assert.Same(t, prev, slog.Default())
+	assert.Equal(t, prevOutput, log.Writer())
+	assert.Equal(t, prevFlags, log.Flags())
+}
+
+func TestCapturePanic(t *testing.T) {
+	prev, prevOutput, prevFlags := slog.Default(), log.Writer(), log.Flags()
+
+	var logs ObservedLogs
+	assert.PanicsWithValue(t, "boom", func() {
+		logs = Capture(func() {
+			slog.Info("before panic")
+			panic("boom")
+		})
+	})
+	assert.Nil(t, logs)
+
+	assert.Same(t, prev, slog.Default())
+	assert.Equal(t, prevOutput, log.Writer())
+	assert.Equal(t, prevFlags, log.Flags())
+
+	// the capture mutex must be released after the panic
+	logs = Capture(func() { slog.Info("after panic") })
+	assert.Equal(t, []string{"after panic"}, Messages(logs))
+}
+
+func TestCaptureT(t *testing.T) {
+	ft := &fakeTB{TB: t}
+	logs := CaptureT(ft, func() {
+		slog.Warn("captured")
+	})
+	assert.Equal(t, []string{"captured"}, Messages(logs))
+
+	ft.failed = true
+	ft.cleanup()
+	assert.Len(t, ft.logs, 2, "observed logs must be dumped on failure")
+}
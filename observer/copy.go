@@ -0,0 +1,115 @@
+package observer
+
+import (
+	"log/slog"
+	"reflect"
+)
+
+// copyAttrs deep copies attribute values recursively, see HandlerOptions.CopyValues.
+func (c contextObserver) copyAttrs(attrs []slog.Attr) []slog.Attr {
+	if !c.opts.CopyValues {
+		return attrs
+	}
+
+	res := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		switch a.Value.Kind() {
+		case slog.KindGroup:
+			a.Value = slog.GroupValue(c.copyAttrs(a.Value.Group())...)
+		case slog.KindAny:
+			a.Value = slog.AnyValue(deepCopy(a.Value.Any()))
+		}
+		res[i] = a
+	}
+	return res
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// deepCopy returns a best-effort deep copy of the value: maps, slices, arrays, pointers and exported
+// struct fields are copied recursively, unexported struct fields are copied shallowly.
+// Errors, channels and functions are returned as is, so that errors identity is kept for errors.Is.
+func deepCopy(v any) any {
+	if v == nil {
+		return nil
+	}
+
+	cp := copier{pointers: make(map[pointerKey]reflect.Value)}
+	return cp.copy(reflect.ValueOf(v)).Interface()
+}
+
+type copier struct {
+	// pointers holds copies of already copied pointers to keep the shared and cyclic references
+	pointers map[pointerKey]reflect.Value
+}
+
+// pointerKey identifies the copied pointer, the type is part of the key as a pointer to a struct
+// and a pointer to its first field have the same address.
+type pointerKey struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+func (cp copier) copy(v reflect.Value) reflect.Value {
+	if v.Type().Implements(errorType) {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		key := pointerKey{addr: v.Pointer(), typ: v.Type()}
+		if c, ok := cp.pointers[key]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		cp.pointers[key] = c
+		c.Elem().Set(cp.copy(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(cp.copy(v.Elem()))
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(cp.copy(iter.Key()), cp.copy(iter.Value()))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cp.copy(v.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cp.copy(v.Index(i)))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(cp.copy(v.Field(i)))
+			}
+		}
+		return c
+	default:
+		return v
+	}
+}
@@ -0,0 +1,119 @@
+package observer
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type copyNode struct {
+	Name     string
+	Tags     []string
+	Next     *copyNode
+	internal map[string]int
+}
+
+func TestCopyValues(t *testing.T) {
+	t.Run("mutated after logging", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{CopyValues: true})
+		logger := slog.New(handler)
+
+		m := map[string]int{"a": 1}
+		buf := []byte("first")
+		logger.With(slog.Any("m", m)).Info("msg", slog.Any("buf", buf), slog.Group("g", slog.Any("m", m)))
+
+		m["a"] = 2
+		m["b"] = 3
+		copy(buf, "xxxxx")
+
+		records := logs.All()
+		require.Len(t, records, 1)
+		assert.Equal(t, map[string]any{
+			"m":   map[string]int{"a": 1},
+			"buf": []byte("first"),
+			"g":   map[string]any{"m": map[string]int{"a": 1}},
+		}, records[0].AttrsMap())
+	})
+
+	t.Run("struct and pointer to its first field", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{CopyValues: true})
+		n := &copyNode{Name: "a"}
+
+		require.NotPanics(t, func() {
+			slog.New(handler).Info("msg", slog.Any("v", struct {
+				S *copyNode
+				P *string
+			}{n, &n.Name}))
+		})
+		assert.Equal(t, 1, logs.Len())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		handler, logs := New(nil)
+		m := map[string]int{"a": 1}
+		slog.New(handler).Info("msg", slog.Any("m", m))
+
+		m["a"] = 2
+		assert.Equal(t, map[string]int{"a": 2}, logs.All()[0].AttrsMap()["m"], "values are stored as is by default")
+	})
+}
+
+func TestDeepCopy(t *testing.T) {
+	t.Run("struct", func(t *testing.T) {
+		n := &copyNode{Name: "a", Tags: []string{"x"}, internal: map[string]int{"i": 1}}
+		n.Next = n
+
+		c := deepCopy(n).(*copyNode)
+		assert.NotSame(t, n, c)
+		assert.Same(t, c, c.Next, "cyclic references are expected to be kept")
+		assert.Equal(t, "a", c.Name)
+
+		n.Name = "b"
+		n.Tags[0] = "y"
+		assert.Equal(t, "a", c.Name)
+		assert.Equal(t, []string{"x"}, c.Tags)
+
+		n.internal["i"] = 2
+		assert.Equal(t, 2, c.internal["i"], "unexported fields are expected to be copied shallowly")
+	})
+
+	t.Run("pointer to the first field", func(t *testing.T) {
+		type pair struct {
+			S *copyNode
+			P *string
+		}
+		n := &copyNode{Name: "a"}
+		v := pair{S: n, P: &n.Name}
+
+		var c pair
+		require.NotPanics(t, func() { c = deepCopy(v).(pair) })
+		assert.Equal(t, "a", c.S.Name)
+		assert.Equal(t, "a", *c.P)
+
+		n.Name = "b"
+		assert.Equal(t, "a", c.S.Name)
+		assert.Equal(t, "a", *c.P)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		err := errors.New("some error")
+		assert.Same(t, err, deepCopy(err), "errors are expected to be kept as is")
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		v := []any{map[string][]int{"a": {1}}, [2]*int{new(int), nil}, nil}
+		c := deepCopy(v).([]any)
+		assert.Equal(t, v, c)
+
+		v[0].(map[string][]int)["a"][0] = 2
+		*v[1].([2]*int)[0] = 3
+		assert.Equal(t, []any{map[string][]int{"a": {1}}, [2]*int{new(int), nil}, nil}, c)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		assert.Nil(t, deepCopy(nil))
+	})
+}
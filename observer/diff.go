@@ -0,0 +1,164 @@
+package observer
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DiffOption configures Diff.
+type DiffOption func(*diffOptions)
+
+type diffOptions struct {
+	compareTime bool
+	ignoreKeys  map[string]struct{}
+}
+
+// DiffCompareTime makes Diff compare records time, it is ignored by default.
+func DiffCompareTime() DiffOption {
+	return func(o *diffOptions) {
+		o.compareTime = true
+	}
+}
+
+// DiffIgnoreKeys makes Diff ignore attributes with the specified keys, keys of the attributes in groups
+// are joined with dots, e.g. "http.status" for slog.Group("http", slog.Int("status", 500)).
+// Ignoring a group key ignores all its attributes.
+func DiffIgnoreKeys(keys ...string) DiffOption {
+	return func(o *diffOptions) {
+		for _, k := range keys {
+			o.ignoreKeys[k] = struct{}{}
+		}
+	}
+}
+
+// Diff compares wanted and got records by level, message and attributes, see LoggedRecord.AttrsMap,
+// and returns human-readable per-record and per-attribute differences suitable for the test failure message.
+// Empty string is returned if there are no differences. Record time is ignored unless DiffCompareTime is set.
+// Records are aligned by the longest common subsequence of the equal ones, so an inserted or removed record
+// is reported on its own instead of shifting all the following ones. Records left between the aligned ones
+// are compared pairwise, the surplus is reported as missing or unexpected.
+func Diff(want, got []LoggedRecord, opts ...DiffOption) string {
+	o := diffOptions{ignoreKeys: make(map[string]struct{})}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of want[i:] and got[j:]
+	lcs := make([][]int, len(want)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(got)+1)
+	}
+	equal := func(i, j int) bool { return len(o.diffRecord(want[i], got[j])) == 0 }
+	for i := len(want) - 1; i >= 0; i-- {
+		for j := len(got) - 1; j >= 0; j-- {
+			if equal(i, j) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		if i < len(want) && j < len(got) && lcs[i][j] == lcs[i+1][j+1]+1 && equal(i, j) {
+			i, j = i+1, j+1
+			continue
+		}
+
+		// collect the gap up to the next aligned pair
+		wi, gj := i, j
+		for i < len(want) && (j == len(got) || lcs[i+1][j] == lcs[i][j]) {
+			i++
+		}
+		for j < len(got) && (i == len(want) || lcs[i][j+1] == lcs[i][j]) {
+			j++
+		}
+		o.diffGap(&sb, want, got, wi, i, gj, j)
+	}
+	return sb.String()
+}
+
+// diffGap writes differences of want[wi:wj] and got[gi:gj] that have no equal records, comparing them pairwise.
+func (o diffOptions) diffGap(sb *strings.Builder, want, got []LoggedRecord, wi, wj, gi, gj int) {
+	for ; wi < wj || gi < gj; wi, gi = wi+1, gi+1 {
+		switch {
+		case gi >= gj:
+			fmt.Fprintf(sb, "record %d: missing, want %s %q %v\n", wi, want[wi].Record.Level, want[wi].Record.Message, want[wi].AttrsMap())
+		case wi >= wj:
+			fmt.Fprintf(sb, "record %d: unexpected %s %q %v\n", gi, got[gi].Record.Level, got[gi].Record.Message, got[gi].AttrsMap())
+		default:
+			if wi == gi {
+				fmt.Fprintf(sb, "record %d:\n", wi)
+			} else {
+				fmt.Fprintf(sb, "record %d (got %d):\n", wi, gi)
+			}
+			for _, l := range o.diffRecord(want[wi], got[gi]) {
+				sb.WriteString("  " + l + "\n")
+			}
+		}
+	}
+}
+
+func (o diffOptions) diffRecord(want, got LoggedRecord) []string {
+	var lines []string
+	if o.compareTime && !want.Record.Time.Equal(got.Record.Time) {
+		lines = append(lines, fmt.Sprintf("time: want %s, got %s",
+			want.Record.Time.Format(time.RFC3339Nano), got.Record.Time.Format(time.RFC3339Nano)))
+	}
+	if want.Record.Level != got.Record.Level {
+		lines = append(lines, fmt.Sprintf("level: want %s, got %s", want.Record.Level, got.Record.Level))
+	}
+	if want.Record.Message != got.Record.Message {
+		lines = append(lines, fmt.Sprintf("msg: want %q, got %q", want.Record.Message, got.Record.Message))
+	}
+
+	wantAttrs, gotAttrs := make(map[string]any), make(map[string]any)
+	o.flatten(wantAttrs, "", want.AttrsMap())
+	o.flatten(gotAttrs, "", got.AttrsMap())
+
+	keys := make([]string, 0, len(wantAttrs)+len(gotAttrs))
+	for k := range wantAttrs {
+		keys = append(keys, k)
+	}
+	for k := range gotAttrs {
+		if _, ok := wantAttrs[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		w, wok := wantAttrs[k]
+		g, gok := gotAttrs[k]
+		switch {
+		case !gok:
+			lines = append(lines, fmt.Sprintf("attr %q: missing, want %v", k, w))
+		case !wok:
+			lines = append(lines, fmt.Sprintf("attr %q: unexpected %v", k, g))
+		case !reflect.DeepEqual(w, g):
+			lines = append(lines, fmt.Sprintf("attr %q: want %v (%T), got %v (%T)", k, w, w, g, g))
+		}
+	}
+	return lines
+}
+
+// flatten puts attributes to res with the keys joined with dots, skipping the ignored ones.
+func (o diffOptions) flatten(res map[string]any, prefix string, attrs map[string]any) {
+	for k, v := range attrs {
+		key := prefix + k
+		if _, ok := o.ignoreKeys[key]; ok {
+			continue
+		}
+
+		if group, ok := v.(map[string]any); ok {
+			o.flatten(res, key+".", group)
+			continue
+		}
+		res[key] = v
+	}
+}
@@ -0,0 +1,93 @@
+package observer
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDiffRecord(ts time.Time, level slog.Level, msg string, attrs ...slog.Attr) LoggedRecord {
+	return LoggedRecord{Record: slog.NewRecord(ts, level, msg, 0), Attrs: attrs}
+}
+
+func TestDiff(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	want := []LoggedRecord{
+		newDiffRecord(time.Time{}, slog.LevelInfo, "connecting", slog.String("host", "db")),
+		newDiffRecord(time.Time{}, slog.LevelWarn, "retry", slog.Int("attempt", 1), slog.Group("http", slog.Int("status", 503))),
+	}
+
+	t.Run("equal", func(t *testing.T) {
+		got := []LoggedRecord{
+			newDiffRecord(ts, slog.LevelInfo, "connecting", slog.String("host", "db")),
+			newDiffRecord(ts, slog.LevelWarn, "retry", slog.Group("http", slog.Int("status", 503)), slog.Int("attempt", 1)),
+		}
+		assert.Empty(t, Diff(want, got))
+		assert.Empty(t, Diff(nil, nil))
+	})
+
+	t.Run("attr value mismatch", func(t *testing.T) {
+		got := []LoggedRecord{
+			want[0],
+			newDiffRecord(time.Time{}, slog.LevelWarn, "retry", slog.Int("attempt", 1), slog.Group("http", slog.Int("status", 500))),
+		}
+		assert.Equal(t, `record 1:
+  attr "http.status": want 503 (int64), got 500 (int64)
+`, Diff(want, got))
+		assert.Empty(t, Diff(want, got, DiffIgnoreKeys("http.status")))
+		assert.Empty(t, Diff(want, got, DiffIgnoreKeys("http")))
+	})
+
+	t.Run("record mismatch", func(t *testing.T) {
+		got := []LoggedRecord{
+			newDiffRecord(ts, slog.LevelError, "connected", slog.String("addr", "db:5432")),
+			want[1],
+		}
+		assert.Equal(t, `record 0:
+  level: want INFO, got ERROR
+  msg: want "connecting", got "connected"
+  attr "addr": unexpected db:5432
+  attr "host": missing, want db
+`, Diff(want, got))
+	})
+
+	t.Run("missing records", func(t *testing.T) {
+		assert.Equal(t, `record 1: missing, want WARN "retry" map[attempt:1 http:map[status:503]]
+`, Diff(want, want[:1]))
+	})
+
+	t.Run("extra records", func(t *testing.T) {
+		got := append(want[:2:2], newDiffRecord(ts, slog.LevelInfo, "done"))
+		assert.Equal(t, `record 2: unexpected INFO "done" map[]
+`, Diff(want, got))
+	})
+
+	t.Run("inserted record", func(t *testing.T) {
+		got := []LoggedRecord{want[0], newDiffRecord(ts, slog.LevelDebug, "resolved"), want[1]}
+		assert.Equal(t, `record 1: unexpected DEBUG "resolved" map[]
+`, Diff(want, got))
+	})
+
+	t.Run("removed record", func(t *testing.T) {
+		assert.Equal(t, `record 0: missing, want INFO "connecting" map[host:db]
+`, Diff(want, want[1:]))
+	})
+
+	t.Run("changed record after removed one", func(t *testing.T) {
+		wantLong := append(want[:2:2], newDiffRecord(time.Time{}, slog.LevelInfo, "done", slog.Int("n", 1)))
+		got := []LoggedRecord{want[1], newDiffRecord(ts, slog.LevelInfo, "done", slog.Int("n", 2))}
+		assert.Equal(t, `record 0: missing, want INFO "connecting" map[host:db]
+record 2 (got 1):
+  attr "n": want 1 (int64), got 2 (int64)
+`, Diff(wantLong, got))
+	})
+
+	t.Run("time", func(t *testing.T) {
+		got := []LoggedRecord{newDiffRecord(ts, slog.LevelInfo, "connecting", slog.String("host", "db"))}
+		assert.Equal(t, `record 0:
+  time: want 0001-01-01T00:00:00Z, got 2024-01-02T03:04:05Z
+`, Diff(want[:1], got, DiffCompareTime()))
+	})
+}
@@ -0,0 +1,171 @@
+package observer
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Expectations is a set of declarative expectations for the observed logs, verified at once with Verify.
+type Expectations struct {
+	logs ObservedLogs
+
+	mu     sync.Mutex
+	items  []*Expectation
+	strict bool
+}
+
+// Expectation describes the logs that are expected to be observed a certain number of times,
+// by default exactly once. All the constraints must be satisfied by the record to match.
+type Expectation struct {
+	matchers []func(LoggedRecord) bool
+	desc     []string
+	min, max int
+}
+
+// Expect creates new Expectations for the observed logs.
+//
+//	exp := observer.Expect(logs)
+//	exp.Message("connected").Times(1)
+//	exp.Level(slog.LevelError).Times(0)
+//	err := exp.Verify()
+func Expect(logs ObservedLogs) *Expectations {
+	return &Expectations{logs: logs}
+}
+
+// Strict makes Verify fail if there are observed logs that do not match any expectation.
+func (e *Expectations) Strict() *Expectations {
+	e.mu.Lock()
+	e.strict = true
+	e.mu.Unlock()
+	return e
+}
+
+// Message adds new expectation for the logs with the specified message.
+func (e *Expectations) Message(msg string) *Expectation {
+	return e.add().Message(msg)
+}
+
+// Level adds new expectation for the logs logged at exactly the specified level.
+func (e *Expectations) Level(level slog.Level) *Expectation {
+	return e.add().Level(level)
+}
+
+// Attr adds new expectation for the logs that have the specified attribute.
+func (e *Expectations) Attr(attr slog.Attr) *Expectation {
+	return e.add().Attr(attr)
+}
+
+func (e *Expectations) add() *Expectation {
+	exp := &Expectation{min: 1, max: 1}
+
+	e.mu.Lock()
+	e.items = append(e.items, exp)
+	e.mu.Unlock()
+	return exp
+}
+
+// Verify checks all the expectations against the observed logs and returns an error with the report
+// of the unmet expectations and the observed logs if any expectation is not met.
+// See assertlog.AssertExpectations to fail the test instead.
+func (e *Expectations) Verify() error {
+	e.mu.Lock()
+	items, strict := e.items, e.strict
+	e.mu.Unlock()
+
+	snapshot := Snapshot(e.logs)
+
+	var report strings.Builder
+	for _, exp := range items {
+		if n := snapshot.Filter(exp.matches).Len(); n < exp.min || (exp.max >= 0 && n > exp.max) {
+			fmt.Fprintf(&report, "  %s: expected %s, observed %d\n", exp, exp.times(), n)
+		}
+	}
+
+	if strict {
+		unexpected := snapshot.Filter(func(r LoggedRecord) bool {
+			for _, exp := range items {
+				if exp.matches(r) {
+					return false
+				}
+			}
+			return true
+		})
+		for _, r := range unexpected.All() {
+			fmt.Fprintf(&report, "  unexpected log: %-5s %q %v\n", r.Record.Level, r.Record.Message, r.AttrsMap())
+		}
+	}
+
+	if report.Len() == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(&report, "observed logs (%d):\n", snapshot.Len())
+	Range(snapshot, func(r LoggedRecord) bool {
+		fmt.Fprintf(&report, "  %-5s %q %v\n", r.Record.Level, r.Record.Message, r.AttrsMap())
+		return true
+	})
+	return fmt.Errorf("log expectations are not met:\n%s", report.String())
+}
+
+// Message constrains the expectation to the logs with the specified message.
+func (exp *Expectation) Message(msg string) *Expectation {
+	return exp.match(fmt.Sprintf("message %q", msg), func(r LoggedRecord) bool {
+		return r.Record.Message == msg
+	})
+}
+
+// Level constrains the expectation to the logs logged at exactly the specified level.
+func (exp *Expectation) Level(level slog.Level) *Expectation {
+	return exp.match("level "+level.String(), func(r LoggedRecord) bool {
+		return r.Record.Level == level
+	})
+}
+
+// Attr constrains the expectation to the logs that have the specified attribute,
+// attributes are compared the same way as ObservedLogs.FilterAttr does.
+func (exp *Expectation) Attr(attr slog.Attr) *Expectation {
+	return exp.match("attr "+attr.String(), func(r LoggedRecord) bool {
+		return filterAttr(r.Attrs, attr)
+	})
+}
+
+// Times expects the matching logs to be observed exactly n times.
+func (exp *Expectation) Times(n int) *Expectation {
+	exp.min, exp.max = n, n
+	return exp
+}
+
+// AtLeast expects the matching logs to be observed at least n times.
+func (exp *Expectation) AtLeast(n int) *Expectation {
+	exp.min, exp.max = n, -1
+	return exp
+}
+
+// String describes the expectation constraints.
+func (exp *Expectation) String() string {
+	return strings.Join(exp.desc, ", ")
+}
+
+func (exp *Expectation) match(desc string, fn func(LoggedRecord) bool) *Expectation {
+	exp.desc = append(exp.desc, desc)
+	exp.matchers = append(exp.matchers, fn)
+	return exp
+}
+
+func (exp *Expectation) matches(r LoggedRecord) bool {
+	for _, m := range exp.matchers {
+		if !m(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (exp *Expectation) times() string {
+	if exp.max < 0 {
+		return fmt.Sprintf("at least %d", exp.min)
+	}
+	return fmt.Sprintf("exactly %d", exp.min)
+}
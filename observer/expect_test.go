@@ -0,0 +1,75 @@
+package observer
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newExpectLogs() ObservedLogs {
+	handler, logs := New(nil)
+	logger := slog.New(handler)
+
+	logger.Info("connecting", slog.String("host", "db"))
+	logger.Warn("retry", slog.Int("attempt", 1))
+	logger.Warn("retry", slog.Int("attempt", 2))
+	logger.Info("connected", slog.String("host", "db"))
+	return logs
+}
+
+func TestExpect(t *testing.T) {
+	logs := newExpectLogs()
+
+	t.Run("met", func(t *testing.T) {
+		exp := Expect(logs)
+		exp.Message("connected").Times(1)
+		exp.Message("connected").Attr(slog.String("host", "db"))
+		exp.Message("retry").AtLeast(1)
+		exp.Level(slog.LevelWarn).Attr(slog.Int("attempt", 2)).Times(1)
+		exp.Level(slog.LevelError).Times(0)
+		exp.Attr(slog.String("host", "db")).Times(2)
+
+		assert.NoError(t, exp.Verify())
+	})
+
+	t.Run("not met", func(t *testing.T) {
+		exp := Expect(logs)
+		exp.Message("connected").Times(1)
+		exp.Message("retry").Times(1)
+		exp.Message("retry").AtLeast(3)
+		exp.Level(slog.LevelWarn).Attr(slog.Int("attempt", 3))
+
+		err := exp.Verify()
+		require.Error(t, err)
+		assert.Equal(t, `log expectations are not met:
+  message "retry": expected exactly 1, observed 2
+  message "retry": expected at least 3, observed 2
+  level WARN, attr attempt=3: expected exactly 1, observed 0
+observed logs (4):
+  INFO  "connecting" map[host:db]
+  WARN  "retry" map[attempt:1]
+  WARN  "retry" map[attempt:2]
+  INFO  "connected" map[host:db]
+`, err.Error())
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		exp := Expect(logs).Strict()
+		exp.Message("connecting")
+		exp.Message("connected")
+
+		err := exp.Verify()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `log expectations are not met:
+  unexpected log: WARN  "retry" map[attempt:1]
+  unexpected log: WARN  "retry" map[attempt:2]
+observed logs (4):
+`)
+
+		exp.Level(slog.LevelWarn).Times(2)
+		assert.NoError(t, exp.Verify())
+	})
+
+}
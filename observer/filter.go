@@ -0,0 +1,138 @@
+package observer
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FilterIndexed returns a copy of the observed logs containing only those entries for which the provided function
+// returns true. The function receives the logical position of the entry in the collection, the oldest entry
+// has position 0. It relies on ObservedLogs.Filter calling the function for each entry in the order they were logged,
+// the same way collections of this package do.
+func FilterIndexed(logs ObservedLogs, keep func(i int, r LoggedRecord) bool) ObservedLogs {
+	var i int
+	return logs.Filter(func(r LoggedRecord) bool {
+		kept := keep(i, r)
+		i++
+		return kept
+	})
+}
+
+// FilterLevels filters entries to those logged at exactly one of the given levels.
+func FilterLevels(logs ObservedLogs, levels ...slog.Level) ObservedLogs {
+	return logs.Filter(func(r LoggedRecord) bool {
+		return slices.Contains(levels, r.Record.Level)
+	})
+}
+
+// FilterByTime filters entries to those that were logged within the [start, end] time range, inclusive.
+// Zero start or end means the range is open on that side.
+func FilterByTime(logs ObservedLogs, start, end time.Time) ObservedLogs {
+	return logs.Filter(func(r LoggedRecord) bool {
+		return inTimeRange(r.Record.Time, start, end)
+	})
+}
+
+// FilterAttrInGroup filters entries to those that have the specified attribute within the group
+// with the specified path, e.g. []string{"http"} for slog.Group("http", slog.Int("status", 500)).
+// Attributes with the same key outside the group are not taken into account.
+func FilterAttrInGroup(logs ObservedLogs, groupPath []string, attr slog.Attr) ObservedLogs {
+	return logs.Filter(func(r LoggedRecord) bool {
+		return filterAttrInGroup(r.Attrs, groupPath, attr)
+	})
+}
+
+// FilterAttrValue filters entries to those that have an attribute with the specified key
+// which value satisfies the provided function.
+func FilterAttrValue(logs ObservedLogs, key string, keep func(slog.Value) bool) ObservedLogs {
+	return logs.Filter(func(r LoggedRecord) bool {
+		return filterAttrValue(r.Attrs, key, keep)
+	})
+}
+
+// FilterAttrValueSnippet filters entries to those that have an attribute with the specified key
+// which value string representation contains the specified snippet.
+func FilterAttrValueSnippet(logs ObservedLogs, key, snippet string) ObservedLogs {
+	return FilterAttrValue(logs, key, func(v slog.Value) bool {
+		return strings.Contains(v.String(), snippet)
+	})
+}
+
+// FilterWithoutFieldKey filters entries to those that do not have the specified key on any nesting level.
+func FilterWithoutFieldKey(logs ObservedLogs, key string) ObservedLogs {
+	return logs.Filter(func(r LoggedRecord) bool {
+		return !hasFieldKeyDeep(r.Attrs, key)
+	})
+}
+
+// FilterAttrKeyPrefix filters entries to those that have an attribute which key starts with the specified
+// prefix on any nesting level. Keys of the attributes in groups are joined with dots, e.g. "http.status"
+// for slog.Group("http", slog.Int("status", 500)), so that the prefix matches flat and grouped keys the same way.
+func FilterAttrKeyPrefix(logs ObservedLogs, prefix string) ObservedLogs {
+	return logs.Filter(func(r LoggedRecord) bool {
+		return hasAttrKeyPrefix(r.Attrs, "", prefix)
+	})
+}
+
+// FilterGroup filters entries to those that have a top-level group with the specified name.
+func FilterGroup(logs ObservedLogs, name string) ObservedLogs {
+	return logs.Filter(func(r LoggedRecord) bool {
+		return filterGroup(r.Attrs, name, false)
+	})
+}
+
+// FilterGroupDeep filters entries to those that have a group with the specified name on any nesting level.
+func FilterGroupDeep(logs ObservedLogs, name string) ObservedLogs {
+	return logs.Filter(func(r LoggedRecord) bool {
+		return filterGroup(r.Attrs, name, true)
+	})
+}
+
+// Distinct filters entries to the first occurrence of each unique record.
+// Records are considered equal when they have the same level, message and attributes, time is ignored.
+func Distinct(logs ObservedLogs) ObservedLogs {
+	return logs.Filter(distinct())
+}
+
+// FilterEmptyAttrs filters entries to those that have no attributes.
+// Empty attributes, e.g. slog.Attr{}, are not taken into account.
+func FilterEmptyAttrs(logs ObservedLogs) ObservedLogs {
+	return logs.Filter(func(r LoggedRecord) bool {
+		return !hasAttrs(r.Attrs)
+	})
+}
+
+// FilterHasAttrs filters entries to those that have at least one non-empty attribute.
+func FilterHasAttrs(logs ObservedLogs) ObservedLogs {
+	return logs.Filter(func(r LoggedRecord) bool {
+		return hasAttrs(r.Attrs)
+	})
+}
+
+// SortedByTime returns a copy of the observed logs sorted by the record time,
+// records with equal time keep the order they were logged in.
+func SortedByTime(logs ObservedLogs) ObservedLogs {
+	records := logs.All()
+	slices.SortStableFunc(records, func(a, b LoggedRecord) int {
+		return a.Record.Time.Compare(b.Record.Time)
+	})
+	return derive(logs, records)
+}
+
+// Reverse returns a copy of the observed logs with the newest records first.
+func Reverse(logs ObservedLogs) ObservedLogs {
+	records := logs.All()
+	slices.Reverse(records)
+	return derive(logs, records)
+}
+
+// derive returns an unbounded writable collection holding the records, the same way as filtered collections
+// are derived from logs: ObservedLogsRing for the ring, ObservedLogsDefault for the others.
+func derive(logs ObservedLogs, records []LoggedRecord) ObservedLogs {
+	if _, ok := logs.(*ObservedLogsRing); ok {
+		return newLinearRing(records)
+	}
+	return newDerivedDefault(records)
+}
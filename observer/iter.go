@@ -0,0 +1,14 @@
+//go:build go1.23
+
+package observer
+
+import "iter"
+
+// Records returns an iterator over the observed logs in the order they were logged, built on Range,
+// so records are not copied if logs implements Ranger. The read lock is held during the iteration then,
+// so the loop body must not call back into the same collection, otherwise it deadlocks.
+func Records(logs ObservedLogs) iter.Seq[LoggedRecord] {
+	return func(yield func(LoggedRecord) bool) {
+		Range(logs, yield)
+	}
+}
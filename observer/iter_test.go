@@ -0,0 +1,36 @@
+//go:build go1.23
+
+package observer
+
+import (
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecords(t *testing.T) {
+	handler, logs := New(&HandlerOptions{ObservedLogs: NewObservedLogsRing(4)})
+	logger := slog.New(handler)
+
+	// ring of 4 wraps and keeps only the last 4 messages
+	for i := 0; i < 6; i++ {
+		logger.Info(fmt.Sprintf("log %d", i))
+	}
+
+	var got []string
+	for r := range Records(logs) {
+		got = append(got, r.Record.Message)
+	}
+	assert.Equal(t, []string{"log 2", "log 3", "log 4", "log 5"}, got)
+
+	got = nil
+	for r := range Records(logs) {
+		got = append(got, r.Record.Message)
+		if len(got) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"log 2", "log 3"}, got)
+}
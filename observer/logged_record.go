@@ -1,34 +1,351 @@
 package observer
 
-import "log/slog"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
 
 // LoggedRecord is a log record representation suitable for direct comparison.
-// Record attributes are extracted to a list to allow comparing them.
+// Record attributes are extracted to a list to allow comparing them. Attributes are in the order
+// slog built-in handlers output them: the ones added with WithAttrs first, then the record ones.
 type LoggedRecord struct {
 	Record slog.Record
 	Attrs  []slog.Attr
+	// Seq is the sequence number assigned by the collection when the record is added if HandlerOptions.AddSeq
+	// is set, it is increasing in the order the records were added, even if they share the same Record.Time.
+	// It is zero otherwise.
+	Seq uint64
+	// Repeated is the number of consecutive identical records that were collapsed into this one
+	// if HandlerOptions.Dedup is set, zero otherwise.
+	Repeated int
+
+	ctx context.Context
+}
+
+// LoggedRecordDelta is a log record paired with the time elapsed since the previous record.
+type LoggedRecordDelta struct {
+	LoggedRecord
+	Delta time.Duration
+}
+
+// Context returns the context passed to the handler if HandlerOptions.AddContext is set, nil otherwise.
+// The context is not exported as a field, but it is still compared when comparing the records with
+// reflect.DeepEqual, e.g. by assert.Equal, use AllUntimed that drops it to compare records with the expected ones.
+func (e LoggedRecord) Context() context.Context {
+	return e.ctx
+}
+
+// Source returns the source code position of the log statement. It returns nil if the position is not available,
+// e.g. when the handler was created without AddSource option, or for the records returned by AllUntimed
+// as they have the program counter zeroed. The position is resolved on the first call and cached.
+func (e LoggedRecord) Source() *slog.Source {
+	if e.Record.PC == 0 {
+		return nil
+	}
+
+	src, ok := sources.Load(e.Record.PC)
+	if !ok {
+		fs := runtime.CallersFrames([]uintptr{e.Record.PC})
+		f, _ := fs.Next()
+		src, _ = sources.LoadOrStore(e.Record.PC, slog.Source{
+			Function: f.Function,
+			File:     f.File,
+			Line:     f.Line,
+		})
+	}
+
+	// return a copy, so that the caller can not change the cached position
+	res := src.(slog.Source)
+	return &res
 }
 
+// sources caches the source code positions resolved by LoggedRecord.Source by the program counter,
+// so that records do not need to carry the cache and stay comparable.
+var sources sync.Map
+
 // AttrsMap returns a map for all attributes in the log record.
-// Groups are recursively converted to maps.
+// Groups are recursively converted to maps, members of the groups with empty key are inlined into the parent,
+// groups without attributes are omitted. slog.LogValuer values are resolved.
+// Duplicate keys on the same level are resolved the same way as decoding slog.JSONHandler output does:
+// the last attribute wins, even if it is a group and the previous one is not, or vice versa.
 func (e LoggedRecord) AttrsMap() map[string]any {
 	return e.attrsMap(e.Attrs)
 }
 
 func (e LoggedRecord) attrsMap(attrs []slog.Attr) map[string]any {
 	res := make(map[string]any, len(attrs))
+	e.fillAttrsMap(res, attrs)
+	return res
+}
+
+func (e LoggedRecord) fillAttrsMap(res map[string]any, attrs []slog.Attr) {
 	for _, a := range attrs {
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			if a.Key == "" {
+				// slog inlines members of the groups with empty key into the parent
+				e.fillAttrsMap(res, v.Group())
+				continue
+			}
+
+			group := e.attrsMap(v.Group())
+			if len(group) == 0 {
+				continue
+			}
+			res[a.Key] = group
+			continue
+		}
+
 		if a.Key == "" {
-			// TODO: implement the full logic that ensures that the value is empty as well.
-			//  The logic is implemented in Attr.isEmpty() method that is private and it checks private field values.
 			continue
 		}
+		res[a.Key] = v.Any()
+	}
+}
+
+// Attr returns the attribute with the specified key, keys of the attributes in groups are joined with dots,
+// e.g. "http.status" for slog.Group("http", slog.Int("status", 500)). Members of the groups with empty key
+// are inlined into the parent, the value is resolved and the last attribute wins on duplicate keys,
+// the same way as for AttrsMap. Returned attribute has the last path element as a key.
+func (e LoggedRecord) Attr(key string) (slog.Attr, bool) {
+	return findAttr(e.Attrs, key)
+}
+
+// AttrString returns the string value of the attribute with the specified key, see Attr.
+// It returns false if there is no such attribute or its value is not a string.
+func (e LoggedRecord) AttrString(key string) (string, bool) {
+	a, ok := e.Attr(key)
+	if !ok || a.Value.Kind() != slog.KindString {
+		return "", false
+	}
+	return a.Value.String(), true
+}
+
+// AttrInt64 returns the int64 value of the attribute with the specified key, see Attr.
+// It returns false if there is no such attribute or its value is not an int64, e.g. added with slog.Int.
+func (e LoggedRecord) AttrInt64(key string) (int64, bool) {
+	a, ok := e.Attr(key)
+	if !ok || a.Value.Kind() != slog.KindInt64 {
+		return 0, false
+	}
+	return a.Value.Int64(), true
+}
+
+// AttrBool returns the bool value of the attribute with the specified key, see Attr.
+// It returns false if there is no such attribute or its value is not a bool.
+func (e LoggedRecord) AttrBool(key string) (bool, bool) {
+	a, ok := e.Attr(key)
+	if !ok || a.Value.Kind() != slog.KindBool {
+		return false, false
+	}
+	return a.Value.Bool(), true
+}
+
+// AttrDuration returns the time.Duration value of the attribute with the specified key, see Attr.
+// It returns false if there is no such attribute or its value is not a duration.
+func (e LoggedRecord) AttrDuration(key string) (time.Duration, bool) {
+	a, ok := e.Attr(key)
+	if !ok || a.Value.Kind() != slog.KindDuration {
+		return 0, false
+	}
+	return a.Value.Duration(), true
+}
+
+// findAttr looks up the attribute by the dot-joined key. Duplicate keys are resolved level by level
+// the same way as for AttrsMap, so that the attribute is found only if it is present in the AttrsMap result.
+// Flat keys containing dots, e.g. slog.String("db.query", ...), are matched as well.
+func findAttr(attrs []slog.Attr, key string) (slog.Attr, bool) {
+	level := levelAttrs(attrs)
+	if a, ok := level[key]; ok {
+		return a, true
+	}
+
+	for i := strings.IndexByte(key, '.'); i >= 0; i = nextDot(key, i) {
+		g, ok := level[key[:i]]
+		if !ok || g.Value.Kind() != slog.KindGroup {
+			continue
+		}
+		if a, ok := findAttr(g.Value.Group(), key[i+1:]); ok {
+			return a, true
+		}
+	}
+	return slog.Attr{}, false
+}
+
+// levelAttrs returns resolved attributes of the same level by their keys, members of the groups with empty key
+// are inlined, groups without attributes are omitted and the last attribute wins, the same way as for AttrsMap.
+func levelAttrs(attrs []slog.Attr) map[string]slog.Attr {
+	res := make(map[string]slog.Attr, len(attrs))
+	fillLevelAttrs(res, attrs)
+	return res
+}
 
+func fillLevelAttrs(res map[string]slog.Attr, attrs []slog.Attr) {
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
 		if a.Value.Kind() == slog.KindGroup {
-			res[a.Key] = e.attrsMap(a.Value.Group())
+			if a.Key == "" {
+				fillLevelAttrs(res, a.Value.Group())
+				continue
+			}
+			if len(levelAttrs(a.Value.Group())) == 0 {
+				continue
+			}
+		}
+
+		if a.Key == "" {
 			continue
 		}
-		res[a.Key] = a.Value.Any()
+		res[a.Key] = a
+	}
+}
+
+// nextDot returns the position of the next dot in the key after the position i, or -1 if there is none.
+func nextDot(key string, i int) int {
+	j := strings.IndexByte(key[i+1:], '.')
+	if j < 0 {
+		return -1
+	}
+	return i + 1 + j
+}
+
+// MarshalJSON implements json.Marshaler: returns a stable JSON object with time, level, message and attributes.
+// Time is omitted if it is zero, e.g. for the records returned by AllUntimed.
+// Groups are converted to nested objects, durations and times are represented as strings.
+func (e LoggedRecord) MarshalJSON() ([]byte, error) {
+	var ts string
+	if !e.Record.Time.IsZero() {
+		ts = e.Record.Time.Format(time.RFC3339Nano)
 	}
+
+	return json.Marshal(struct {
+		Time    string         `json:"time,omitempty"`
+		Level   string         `json:"level"`
+		Message string         `json:"msg"`
+		Attrs   map[string]any `json:"attrs"`
+	}{
+		Time:    ts,
+		Level:   e.Record.Level.String(),
+		Message: e.Record.Message,
+		Attrs:   jsonAttrsMap(e.Attrs),
+	})
+}
+
+// Map returns the record as a map in the same shape as decoding slog.JSONHandler output does:
+// slog.TimeKey (omitted if time is zero), slog.LevelKey, slog.MessageKey, slog.SourceKey (omitted if
+// the source is not available) and attributes on the top level, groups as nested maps.
+// Attribute values are resolved and converted the same way as MarshalJSON does, empty groups are omitted.
+// The result is suitable for testing/slogtest harness.
+func (e LoggedRecord) Map() map[string]any {
+	res := make(map[string]any, len(e.Attrs)+4)
+	if !e.Record.Time.IsZero() {
+		res[slog.TimeKey] = e.Record.Time.Format(time.RFC3339Nano)
+	}
+	res[slog.LevelKey] = e.Record.Level.String()
+	res[slog.MessageKey] = e.Record.Message
+	if src := e.Source(); src != nil {
+		res[slog.SourceKey] = map[string]any{"function": src.Function, "file": src.File, "line": src.Line}
+	}
+
+	fillJSONAttrsMap(res, e.Attrs)
 	return res
 }
+
+// jsonAttrsMap is similar to LoggedRecord.attrsMap, but converts values to JSON-friendly representation
+// and omits empty groups the same way slog.JSONHandler does.
+func jsonAttrsMap(attrs []slog.Attr) map[string]any {
+	res := make(map[string]any, len(attrs))
+	fillJSONAttrsMap(res, attrs)
+	return res
+}
+
+func fillJSONAttrsMap(res map[string]any, attrs []slog.Attr) {
+	for _, a := range attrs {
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindGroup && a.Key == "" {
+			fillJSONAttrsMap(res, v.Group())
+			continue
+		}
+
+		if a.Key == "" {
+			continue
+		}
+
+		switch v.Kind() {
+		case slog.KindGroup:
+			group := jsonAttrsMap(v.Group())
+			if len(group) == 0 {
+				continue
+			}
+			res[a.Key] = group
+		case slog.KindDuration:
+			res[a.Key] = v.Duration().String()
+		case slog.KindTime:
+			res[a.Key] = v.Time().Format(time.RFC3339Nano)
+		case slog.KindAny:
+			if err, ok := v.Any().(error); ok {
+				res[a.Key] = err.Error()
+				continue
+			}
+			res[a.Key] = v.Any()
+		default:
+			res[a.Key] = v.Any()
+		}
+	}
+}
+
+// String returns the record in a human-readable format, the same way as slog.TextHandler renders it,
+// but without time, e.g. "level=INFO msg=foo i=1 foo.bar.i=3".
+func (e LoggedRecord) String() string {
+	var sb strings.Builder
+	h := slog.NewTextHandler(&sb, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+
+	rc := e.Record.Clone()
+	rc.AddAttrs(e.Attrs...)
+	if err := h.Handle(context.Background(), rc); err != nil {
+		return fmt.Sprintf("level=%s msg=%q !error=%q", e.Record.Level, e.Record.Message, err)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// size returns approximate size of the record in bytes, estimated from its message and attributes.
+func (e LoggedRecord) size() int {
+	return len(e.Record.Message) + attrsSize(e.Attrs)
+}
+
+func attrsSize(attrs []slog.Attr) int {
+	var n int
+	for _, a := range attrs {
+		n += len(a.Key)
+		switch a.Value.Kind() {
+		case slog.KindString:
+			n += len(a.Value.String())
+		case slog.KindGroup:
+			n += attrsSize(a.Value.Group())
+		case slog.KindAny, slog.KindLogValuer:
+			n += len(fmt.Sprint(a.Value.Any()))
+		default:
+			n += 8
+		}
+	}
+	return n
+}
+
+// isEmptyAttr reports whether the attribute is an empty one, e.g. slog.Attr{}.
+// Handlers are expected to ignore such attributes.
+func isEmptyAttr(a slog.Attr) bool {
+	return a.Key == "" && a.Value.Kind() == slog.KindAny && a.Value.Any() == nil
+}
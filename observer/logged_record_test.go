@@ -1,10 +1,17 @@
 package observer
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoggedEntryContextMap(t *testing.T) {
@@ -65,6 +72,62 @@ func TestLoggedEntryContextMap(t *testing.T) {
 			},
 			want: map[string]any{},
 		},
+		{
+			msg: "empty key group",
+			attrs: []slog.Attr{
+				slog.String("k1", "v1"),
+				slog.Group("", slog.String("k2", "v2"), slog.String("k1", "v3")),
+			},
+			want: map[string]any{
+				"k1": "v3",
+				"k2": "v2",
+			},
+		},
+		{
+			msg: "nested empty key groups",
+			attrs: []slog.Attr{
+				slog.Group("level1",
+					slog.String("k1", "v1"),
+					slog.Group("",
+						slog.String("k2", "v2"),
+						slog.Group("", slog.String("k3", "v3")),
+						slog.Group("level2", slog.Group("", slog.String("k4", "v4"))),
+					),
+				),
+				slog.Group("", slog.Group("", slog.String("k5", "v5"))),
+			},
+			want: map[string]any{
+				"level1": map[string]any{
+					"k1": "v1",
+					"k2": "v2",
+					"k3": "v3",
+					"level2": map[string]any{
+						"k4": "v4",
+					},
+				},
+				"k5": "v5",
+			},
+		},
+		{
+			msg: "empty groups",
+			attrs: []slog.Attr{
+				slog.String("k1", "v1"),
+				slog.Group("g1"),
+				slog.Group("g2", slog.Group("g3"), slog.Group("")),
+			},
+			want: map[string]any{
+				"k1": "v1",
+			},
+		},
+		{
+			msg: "log valuer",
+			attrs: []slog.Attr{
+				slog.Any("k1", stringValuer("v1")),
+			},
+			want: map[string]any{
+				"k1": "v1",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -74,3 +137,330 @@ func TestLoggedEntryContextMap(t *testing.T) {
 		})
 	}
 }
+
+func logFromHelper(logger *slog.Logger) {
+	logger.Info("from helper")
+}
+
+func TestLoggedRecordSource(t *testing.T) {
+	t.Run("AddSource", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{AddSource: true})
+		logFromHelper(slog.New(handler))
+
+		records := logs.TakeAll()
+		require.Len(t, records, 1)
+
+		src := records[0].Source()
+		require.NotNil(t, src)
+		assert.True(t, strings.HasSuffix(src.Function, "observer.logFromHelper"), src.Function)
+		assert.Equal(t, "logged_record_test.go", filepath.Base(src.File))
+		assert.NotZero(t, src.Line)
+
+		src.Line = 0
+		assert.NotZero(t, records[0].Source().Line, "cached position must not be changed by the caller")
+	})
+
+	t.Run("AllUntimed", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{AddSource: true})
+		logFromHelper(slog.New(handler))
+
+		require.NotNil(t, logs.All()[0].Source())
+		assert.Equal(t, []LoggedRecord{
+			{Record: slog.NewRecord(time.Time{}, slog.LevelInfo, "from helper", 0)},
+		}, logs.AllUntimed())
+		assert.Nil(t, logs.AllUntimed()[0].Source())
+	})
+
+	t.Run("no AddSource", func(t *testing.T) {
+		handler, logs := New(nil)
+		logFromHelper(slog.New(handler))
+
+		records := logs.TakeAll()
+		require.Len(t, records, 1)
+		assert.Nil(t, records[0].Source())
+	})
+}
+
+func TestLoggedRecordMarshalJSON(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	record := LoggedRecord{
+		Record: slog.NewRecord(ts, slog.LevelWarn, "hello", 0),
+		Attrs: []slog.Attr{
+			slog.String("s", "v"),
+			slog.Int("i", 42),
+			slog.Bool("b", true),
+			slog.Duration("d", 1500*time.Millisecond),
+			slog.Time("t", ts),
+			slog.Any("err", errors.New("some error")),
+			slog.Any("slice", []string{"a", "b"}),
+			slog.Group("g", slog.Float64("f", 1.5), slog.Group("nested", slog.String("k", "v"))),
+			{},
+		},
+	}
+
+	got, err := json.Marshal(record)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"time": "2024-01-02T03:04:05.000000006Z",
+		"level": "WARN",
+		"msg": "hello",
+		"attrs": {
+			"s": "v",
+			"i": 42,
+			"b": true,
+			"d": "1.5s",
+			"t": "2024-01-02T03:04:05.000000006Z",
+			"err": "some error",
+			"slice": ["a", "b"],
+			"g": {"f": 1.5, "nested": {"k": "v"}}
+		}
+	}`, string(got))
+
+	// untimed record omits time, and output is stable
+	record.Record.Time = time.Time{}
+	got1, err := json.Marshal(record)
+	require.NoError(t, err)
+	got2, err := json.Marshal(record)
+	require.NoError(t, err)
+	assert.Equal(t, got1, got2)
+	assert.NotContains(t, string(got1), `"time"`)
+}
+
+type stringValuer string
+
+func (v stringValuer) LogValue() slog.Value {
+	return slog.StringValue(string(v))
+}
+
+func TestLoggedRecordMap(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	record := LoggedRecord{
+		Record: slog.NewRecord(ts, slog.LevelWarn, "hello", 0),
+		Attrs: []slog.Attr{
+			slog.String("s", "v"),
+			slog.Duration("d", 1500*time.Millisecond),
+			slog.Any("valuer", stringValuer("resolved")),
+			slog.Group("g", slog.Int("i", 42), slog.Group("empty")),
+			slog.Group("", slog.String("inlined", "v")),
+			slog.Group("empty"),
+			{},
+		},
+	}
+
+	assert.Equal(t, map[string]any{
+		slog.TimeKey:    "2024-01-02T03:04:05.000000006Z",
+		slog.LevelKey:   "WARN",
+		slog.MessageKey: "hello",
+		"s":             "v",
+		"d":             "1.5s",
+		"valuer":        "resolved",
+		"g":             map[string]any{"i": int64(42)},
+		"inlined":       "v",
+	}, record.Map())
+
+	record.Record.Time = time.Time{}
+	assert.NotContains(t, record.Map(), slog.TimeKey)
+	assert.NotContains(t, record.Map(), slog.SourceKey)
+}
+
+func TestLoggedRecordAttrsMapDuplicates(t *testing.T) {
+	tests := []struct {
+		msg string
+		log func(logger *slog.Logger)
+	}{
+		{
+			msg: "top-level duplicates",
+			log: func(logger *slog.Logger) {
+				logger.Info("msg", slog.Int("a", 1), slog.Int("a", 2))
+			},
+		},
+		{
+			msg: "handler duplicates",
+			log: func(logger *slog.Logger) {
+				logger.With(slog.Int("a", 1)).With(slog.Int("a", 2)).Info("msg")
+			},
+		},
+		{
+			msg: "duplicates in group",
+			log: func(logger *slog.Logger) {
+				logger.Info("msg", slog.Group("g", slog.Int("a", 1), slog.Int("a", 2)))
+			},
+		},
+		{
+			msg: "duplicate groups",
+			log: func(logger *slog.Logger) {
+				logger.Info("msg", slog.Group("g", slog.Int("a", 1)), slog.Group("g", slog.Int("b", 2)))
+			},
+		},
+		{
+			msg: "group overwritten by value",
+			log: func(logger *slog.Logger) {
+				logger.Info("msg", slog.Group("g", slog.Int("a", 1)), slog.Int("g", 2))
+			},
+		},
+		{
+			msg: "value overwritten by group",
+			log: func(logger *slog.Logger) {
+				logger.Info("msg", slog.Int("g", 2), slog.Group("g", slog.Int("a", 1)))
+			},
+		},
+		{
+			msg: "WithGroup handler and record duplicates",
+			log: func(logger *slog.Logger) {
+				logger.WithGroup("g").With(slog.Int("a", 1)).Info("msg", slog.Int("a", 2))
+			},
+		},
+		{
+			msg: "handler group and WithGroup",
+			log: func(logger *slog.Logger) {
+				logger.With(slog.Group("g", slog.Int("a", 1))).WithGroup("g").Info("msg", slog.Int("b", 2))
+			},
+		},
+		{
+			msg: "inlined group duplicates",
+			log: func(logger *slog.Logger) {
+				logger.Info("msg", slog.Int("a", 1), slog.Group("", slog.Int("a", 2), slog.Group("", slog.Int("b", 3))))
+			},
+		},
+		{
+			msg: "nested WithGroup duplicates",
+			log: func(logger *slog.Logger) {
+				logger.WithGroup("g").With(slog.Int("a", 1), slog.Group("h", slog.Int("b", 1))).
+					WithGroup("h").With(slog.Int("b", 2)).Info("msg", slog.Int("a", 3))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+			var want map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &want))
+			delete(want, slog.TimeKey)
+			delete(want, slog.LevelKey)
+			delete(want, slog.MessageKey)
+
+			handler, logs := New(nil)
+			tt.log(slog.New(handler))
+			records := logs.TakeAll()
+			require.Len(t, records, 1)
+
+			// normalize value types with JSON round trip
+			gotJSON, err := json.Marshal(records[0].AttrsMap())
+			require.NoError(t, err)
+			var got map[string]any
+			require.NoError(t, json.Unmarshal(gotJSON, &got))
+
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestFilterAttrDuplicates(t *testing.T) {
+	handler, logs := New(nil)
+	logger := slog.New(handler)
+
+	logger.Info("overwritten", slog.Int("a", 1), slog.Int("a", 2))
+	logger.Info("overwritten in group", slog.Group("g", slog.Int("a", 1), slog.Int("a", 2)))
+	logger.Info("overwritten group", slog.Group("g", slog.Int("a", 1)), slog.Group("g", slog.Int("a", 2)))
+
+	assert.Empty(t, Messages(logs.FilterAttr(slog.Int("a", 1))))
+	assert.Equal(t, Messages(logs), Messages(logs.FilterAttr(slog.Int("a", 2))))
+	assert.Empty(t, Messages(FilterAttrValue(logs, "a", func(v slog.Value) bool { return v.Int64() == 1 })))
+}
+
+func TestLoggedRecordString(t *testing.T) {
+	record := LoggedRecord{
+		Record: slog.NewRecord(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), slog.LevelInfo, "hello world", 0),
+		Attrs: []slog.Attr{
+			slog.Int("i", 1),
+			slog.Group("foo", slog.Group("bar", slog.String("s", "with space"))),
+			slog.Group("empty"),
+		},
+	}
+	assert.Equal(t, `level=INFO msg="hello world" i=1 foo.bar.s="with space"`, record.String())
+	assert.Equal(t, "level=ERROR msg=\"\"", LoggedRecord{Record: slog.NewRecord(time.Time{}, slog.LevelError, "", 0)}.String())
+}
+
+func TestLoggedRecordAttr(t *testing.T) {
+	record := LoggedRecord{Attrs: []slog.Attr{
+		slog.String("s", "v"),
+		slog.Int("i", 1),
+		slog.Bool("b", true),
+		slog.Duration("d", time.Second),
+		slog.Any("valuer", stringValuer("resolved")),
+		slog.String("db.query", "select 1"),
+		slog.Group("http", slog.Int("status", 500), slog.Group("req", slog.String("path", "/"))),
+		slog.Group("", slog.String("inlined", "v")),
+		slog.Group("empty"),
+		slog.Int("dup", 1),
+		slog.Int("dup", 2),
+	}}
+
+	a, ok := record.Attr("http.req.path")
+	assert.True(t, ok)
+	assert.Equal(t, slog.String("path", "/"), a)
+
+	a, ok = record.Attr("http")
+	assert.True(t, ok)
+	assert.Equal(t, "http", a.Key)
+
+	for _, key := range []string{"missing", "http.missing", "empty", "http.status.code", ""} {
+		_, ok = record.Attr(key)
+		assert.False(t, ok, key)
+	}
+
+	s, ok := record.AttrString("s")
+	assert.True(t, ok)
+	assert.Equal(t, "v", s)
+	s, _ = record.AttrString("valuer")
+	assert.Equal(t, "resolved", s)
+	s, _ = record.AttrString("db.query")
+	assert.Equal(t, "select 1", s)
+	s, _ = record.AttrString("inlined")
+	assert.Equal(t, "v", s)
+	_, ok = record.AttrString("i")
+	assert.False(t, ok, "wrong kind")
+
+	i, ok := record.AttrInt64("http.status")
+	assert.True(t, ok)
+	assert.Equal(t, int64(500), i)
+	i, _ = record.AttrInt64("dup")
+	assert.Equal(t, int64(2), i, "last attribute wins")
+
+	b, ok := record.AttrBool("b")
+	assert.True(t, ok)
+	assert.True(t, b)
+
+	d, ok := record.AttrDuration("d")
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, d)
+	_, ok = record.AttrDuration("s")
+	assert.False(t, ok)
+
+	t.Run("last wins", func(t *testing.T) {
+		record := LoggedRecord{Attrs: []slog.Attr{
+			slog.Group("http", slog.Int("status", 500)),
+			slog.Group("http", slog.String("method", "GET")),
+			slog.Group("db", slog.String("table", "users")),
+			slog.String("db", "postgres"),
+			slog.String("cache", "redis"),
+			slog.Group("cache", slog.Group("empty")),
+		}}
+		attrs := record.AttrsMap()
+
+		_, ok := record.Attr("http.status")
+		assert.False(t, ok, "the later group without the attribute wins")
+		assert.NotContains(t, attrs["http"], "status")
+
+		_, ok = record.Attr("db.table")
+		assert.False(t, ok, "the later non-group attribute wins")
+		s, _ := record.AttrString("db")
+		assert.Equal(t, attrs["db"], s)
+
+		s, _ = record.AttrString("cache")
+		assert.Equal(t, attrs["cache"], s, "groups without attributes are omitted")
+	})
+}
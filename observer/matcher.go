@@ -0,0 +1,154 @@
+package observer
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// RecordMatcher matches LoggedRecord by level, message and attributes, ignoring the record time.
+// It implements gomock.Matcher and gomock.GotFormatter interfaces and can be used with gomega
+// matchers, e.g. WithTransform(RecordMatcher.Matches, BeTrue()).
+type RecordMatcher struct {
+	msg   string
+	level slog.Level
+	attrs map[string]any
+}
+
+// MatchRecord returns RecordMatcher for the records with the specified message and level
+// that have at least the specified attributes. Attributes are compared with LoggedRecord.AttrsMap
+// using subset semantics: groups are nested maps, extra attributes of the record are ignored.
+// Integer and float values are compared by value regardless of their type, e.g. 1 matches int64(1).
+func MatchRecord(msg string, level slog.Level, attrs map[string]any) RecordMatcher {
+	return RecordMatcher{msg: msg, level: level, attrs: attrs}
+}
+
+// Matches reports whether x is LoggedRecord or *LoggedRecord that satisfies the matcher.
+func (m RecordMatcher) Matches(x any) bool {
+	return m.mismatch(x) == ""
+}
+
+// String describes the matcher.
+func (m RecordMatcher) String() string {
+	return fmt.Sprintf("is %s %q record with attrs %v", m.level, m.msg, m.attrs)
+}
+
+// Got describes x and the reason it does not satisfy the matcher, if any.
+func (m RecordMatcher) Got(x any) string {
+	r, ok := asLoggedRecord(x)
+	if !ok {
+		return fmt.Sprintf("%v (%T)", x, x)
+	}
+
+	got := fmt.Sprintf("%s %q record with attrs %v", r.Record.Level, r.Record.Message, r.AttrsMap())
+	if reason := m.mismatch(x); reason != "" {
+		got += ": " + reason
+	}
+	return got
+}
+
+// mismatch returns the reason x does not satisfy the matcher or empty string if it does.
+func (m RecordMatcher) mismatch(x any) string {
+	r, ok := asLoggedRecord(x)
+	if !ok {
+		return fmt.Sprintf("%T is not a LoggedRecord", x)
+	}
+
+	var reasons []string
+	if r.Record.Level != m.level {
+		reasons = append(reasons, fmt.Sprintf("level %s != %s", r.Record.Level, m.level))
+	}
+	if r.Record.Message != m.msg {
+		reasons = append(reasons, fmt.Sprintf("message %q != %q", r.Record.Message, m.msg))
+	}
+	reasons = append(reasons, attrsMismatch("", r.AttrsMap(), m.attrs)...)
+	return strings.Join(reasons, ", ")
+}
+
+func asLoggedRecord(x any) (LoggedRecord, bool) {
+	switch r := x.(type) {
+	case LoggedRecord:
+		return r, true
+	case *LoggedRecord:
+		if r != nil {
+			return *r, true
+		}
+	}
+	return LoggedRecord{}, false
+}
+
+// attrsMismatch returns the reasons the got attributes map is not a superset of the want one,
+// keys of the nested groups are reported with their path, e.g. "http.status".
+func attrsMismatch(prefix string, got, want map[string]any) []string {
+	keys := make([]string, 0, len(want))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var reasons []string
+	for _, k := range keys {
+		path := prefix + k
+		g, ok := got[k]
+		if !ok {
+			reasons = append(reasons, fmt.Sprintf("attr %q is missing", path))
+			continue
+		}
+
+		wantGroup, isWantGroup := want[k].(map[string]any)
+		gotGroup, isGotGroup := g.(map[string]any)
+		if isWantGroup && isGotGroup {
+			reasons = append(reasons, attrsMismatch(path+".", gotGroup, wantGroup)...)
+			continue
+		}
+
+		if !attrValueEqual(g, want[k]) {
+			reasons = append(reasons, fmt.Sprintf("attr %q %v != %v", path, g, want[k]))
+		}
+	}
+	return reasons
+}
+
+// attrValueEqual compares attribute values, numbers are compared by value regardless of their type.
+func attrValueEqual(got, want any) bool {
+	if reflect.DeepEqual(got, want) {
+		return true
+	}
+
+	gv, wv := reflect.ValueOf(got), reflect.ValueOf(want)
+	switch {
+	case isInt(gv) && isInt(wv):
+		return gv.Int() == wv.Int()
+	case isNumber(gv) && isNumber(wv):
+		return toFloat(gv) == toFloat(wv)
+	}
+	return false
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isNumber(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return isInt(v)
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return float64(v.Int())
+}
@@ -0,0 +1,74 @@
+package observer
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gomockMatcher mirrors gomock.Matcher and gomock.GotFormatter interfaces.
+type gomockMatcher interface {
+	Matches(x any) bool
+	String() string
+	Got(got any) string
+}
+
+var _ gomockMatcher = RecordMatcher{}
+
+func TestMatchRecord(t *testing.T) {
+	handler, logs := New(nil)
+	logger := slog.New(handler).With(slog.String("component", "http"))
+
+	logger.Warn("request failed", slog.Int("attempt", 2), slog.Group("http", slog.Int("status", 503), slog.String("method", "GET")))
+
+	records := logs.All()
+	require.Len(t, records, 1)
+	r := records[0]
+
+	t.Run("plain", func(t *testing.T) {
+		assert.True(t, MatchRecord("request failed", slog.LevelWarn, nil).Matches(r))
+		assert.True(t, MatchRecord("request failed", slog.LevelWarn, map[string]any{
+			"attempt": 2,
+			"http":    map[string]any{"status": 503},
+		}).Matches(&r))
+
+		// time is ignored
+		untimed := logs.AllUntimed()[0]
+		assert.True(t, MatchRecord("request failed", slog.LevelWarn, map[string]any{"attempt": int64(2)}).Matches(untimed))
+
+		assert.False(t, MatchRecord("request failed", slog.LevelError, nil).Matches(r))
+		assert.False(t, MatchRecord("request", slog.LevelWarn, nil).Matches(r))
+		assert.False(t, MatchRecord("request failed", slog.LevelWarn, map[string]any{"attempt": "2"}).Matches(r))
+		assert.False(t, MatchRecord("request failed", slog.LevelWarn, map[string]any{"retry": true}).Matches(r))
+		assert.False(t, MatchRecord("request failed", slog.LevelWarn, nil).Matches("request failed"))
+		assert.False(t, MatchRecord("request failed", slog.LevelWarn, nil).Matches((*LoggedRecord)(nil)))
+	})
+
+	t.Run("gomock", func(t *testing.T) {
+		var m gomockMatcher = MatchRecord("request failed", slog.LevelError, map[string]any{
+			"component": "http",
+			"http":      map[string]any{"status": 500, "path": "/"},
+		})
+
+		assert.False(t, m.Matches(r))
+		assert.Equal(t, `is ERROR "request failed" record with attrs map[component:http http:map[path:/ status:500]]`, m.String())
+		assert.Equal(t,
+			`WARN "request failed" record with attrs map[attempt:2 component:http http:map[method:GET status:503]]: `+
+				`level WARN != ERROR, attr "http.path" is missing, attr "http.status" 503 != 500`,
+			m.Got(r),
+		)
+		assert.Equal(t, "foo (string)", m.Got("foo"))
+	})
+
+	t.Run("numbers", func(t *testing.T) {
+		rec := LoggedRecord{
+			Record: slog.NewRecord(time.Time{}, slog.LevelInfo, "numbers", 0),
+			Attrs:  []slog.Attr{slog.Uint64("u", 3), slog.Float64("f", 1.5), slog.Int("i", -1)},
+		}
+		assert.True(t, MatchRecord("numbers", slog.LevelInfo, map[string]any{"u": 3, "f": 1.5, "i": int8(-1)}).Matches(rec))
+		assert.False(t, MatchRecord("numbers", slog.LevelInfo, map[string]any{"f": 1}).Matches(rec))
+	})
+}
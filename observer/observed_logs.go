@@ -0,0 +1,393 @@
+package observer
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// WithDeltas returns a copy of all the observed logs paired with the time elapsed since the previous record.
+// Delta is zero for the first record and for records with zero time. Records with zero time
+// are skipped when looking for the previous record.
+func WithDeltas(logs ObservedLogs) []LoggedRecordDelta {
+	records := logs.All()
+	res := make([]LoggedRecordDelta, len(records))
+	var prev time.Time
+	for i, r := range records {
+		res[i].LoggedRecord = r
+		if r.Record.Time.IsZero() {
+			continue
+		}
+		if !prev.IsZero() {
+			res[i].Delta = r.Record.Time.Sub(prev)
+		}
+		prev = r.Record.Time
+	}
+	return res
+}
+
+// Dump writes all the observed logs to w in a human-readable format, one record per line,
+// the same way as slog.TextHandler does, e.g. "level=INFO msg=foo i=1 foo.bar.i=3".
+func Dump(w io.Writer, logs ObservedLogs) error {
+	h := slog.NewTextHandler(w, nil)
+	for _, r := range logs.All() {
+		rc := r.Record.Clone()
+		rc.AddAttrs(r.Attrs...)
+		if err := h.Handle(context.Background(), rc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Range calls fn for each observed log in the order they were logged, iteration stops when fn returns false.
+// Records are not copied if logs implements Ranger, see its restrictions, otherwise Range iterates over All.
+func Range(logs ObservedLogs, fn func(LoggedRecord) bool) {
+	if r, ok := logs.(Ranger); ok {
+		r.Range(fn)
+		return
+	}
+
+	for _, r := range logs.All() {
+		if !fn(r) {
+			return
+		}
+	}
+}
+
+// AddRecords stores already prepared records in the given order, see RecordsAdder.
+// Collections that do not implement RecordsAdder get the records with Add, so that only the record
+// and its attributes are kept, e.g. LoggedRecord.Seq is assigned by the collection if needed.
+func AddRecords(logs ObservedLogs, records []LoggedRecord) {
+	if a, ok := logs.(RecordsAdder); ok {
+		a.AddRecords(records)
+		return
+	}
+
+	for _, r := range records {
+		logs.Add(r.Record, r.Attrs)
+	}
+}
+
+// Cap returns the maximum number of records the collection holds, see Limiter.
+// It returns 0, i.e. unbounded, for the collections that do not implement Limiter.
+func Cap(logs ObservedLogs) int {
+	if l, ok := logs.(Limiter); ok {
+		return l.Cap()
+	}
+	return 0
+}
+
+// Bounded reports whether the collection drops records because of its limits, see Limiter.
+// It returns false for the collections that do not implement Limiter.
+func Bounded(logs ObservedLogs) bool {
+	if l, ok := logs.(Limiter); ok {
+		return l.Bounded()
+	}
+	return false
+}
+
+// Total returns the number of records ever added to the collection, see TotalCounter.
+// It returns the number of records in the collection for the collections that do not implement TotalCounter.
+func Total(logs ObservedLogs) uint64 {
+	if c, ok := logs.(TotalCounter); ok {
+		return c.Total()
+	}
+	return uint64(logs.Len())
+}
+
+// TakeN returns up to n oldest observed logs and removes them from the collection, see Truncater.
+// Collections that do not implement Truncater are truncated with TakeAll and the rest of the records
+// are added back with AddRecords, so records logged in the meantime may end up before them.
+func TakeN(logs ObservedLogs, n int) []LoggedRecord {
+	if t, ok := logs.(Truncater); ok {
+		return t.TakeN(n)
+	}
+
+	records := logs.TakeAll()
+	n = min(max(n, 0), len(records))
+	if n < len(records) {
+		AddRecords(logs, records[n:])
+	}
+	return records[:n:n]
+}
+
+// Reset truncates the observed logs without returning them, see Truncater.
+// Collections that do not implement Truncater are truncated with TakeAll.
+func Reset(logs ObservedLogs) {
+	if t, ok := logs.(Truncater); ok {
+		t.Reset()
+		return
+	}
+	logs.TakeAll()
+}
+
+// CountByLevel returns the number of observed logs per level.
+func CountByLevel(logs ObservedLogs) map[slog.Level]int {
+	counts := make(map[slog.Level]int)
+	Range(logs, func(r LoggedRecord) bool {
+		counts[r.Record.Level]++
+		return true
+	})
+	return counts
+}
+
+// Count returns the number of observed logs that satisfy the provided function, it is the same as
+// logs.Filter(pred).Len(), but does not copy the matched records of the collections of this package.
+func Count(logs ObservedLogs, pred func(LoggedRecord) bool) int {
+	// concrete types are switched on instead of calling an interface method, so that pred does not escape
+	switch l := logs.(type) {
+	case *ObservedLogsDefault:
+		return l.count(pred)
+	case *ObservedLogsRing:
+		return l.count(pred)
+	}
+
+	var n int
+	for _, r := range logs.All() {
+		if pred(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// Any reports whether there is an observed log that satisfies the provided function,
+// it stops at the first match.
+func Any(logs ObservedLogs, pred func(LoggedRecord) bool) bool {
+	switch l := logs.(type) {
+	case *ObservedLogsDefault:
+		return l.contains(pred)
+	case *ObservedLogsRing:
+		return l.contains(pred)
+	}
+
+	for _, r := range logs.All() {
+		if pred(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// None reports whether there is no observed log that satisfies the provided function,
+// it stops at the first match.
+func None(logs ObservedLogs, pred func(LoggedRecord) bool) bool {
+	return !Any(logs, pred)
+}
+
+// At returns the observed log at the logical position i, the oldest one has position 0.
+// Negative positions count from the end, e.g. At(logs, -1) returns the latest log.
+// The second return value is false if there is no log at the position.
+// Collections that do not implement Indexer are copied with All.
+func At(logs ObservedLogs, i int) (LoggedRecord, bool) {
+	if ix, ok := logs.(Indexer); ok {
+		return ix.At(i)
+	}
+
+	records := logs.All()
+	i, ok := logicalIndex(i, len(records))
+	if !ok {
+		return LoggedRecord{}, false
+	}
+	return records[i], true
+}
+
+// Last returns a copy of up to n latest observed logs in the order they were logged.
+// Collections that do not implement Indexer are copied with All.
+func Last(logs ObservedLogs, n int) []LoggedRecord {
+	if ix, ok := logs.(Indexer); ok {
+		return ix.Last(n)
+	}
+
+	records := logs.All()
+	return records[len(records)-min(max(n, 0), len(records)):]
+}
+
+// Latest returns the latest observed log, the second return value is false if the collection is empty.
+// It is the same as At(logs, -1).
+func Latest(logs ObservedLogs) (LoggedRecord, bool) {
+	return At(logs, -1)
+}
+
+// logicalIndex converts possibly negative position to the index in the collection of length n.
+func logicalIndex(i, n int) (int, bool) {
+	if i < 0 {
+		i += n
+	}
+	return i, i >= 0 && i < n
+}
+
+// Messages returns messages of all the observed logs in the order they were logged.
+func Messages(logs ObservedLogs) []string {
+	all := logs.All()
+	ret := make([]string, len(all))
+	for i := range all {
+		ret[i] = all[i].Record.Message
+	}
+	return ret
+}
+
+// AttrsMaps returns attributes maps of all the observed logs in the order they were logged.
+// See LoggedRecord.AttrsMap for details.
+func AttrsMaps(logs ObservedLogs) []map[string]any {
+	all := logs.All()
+	ret := make([]map[string]any, len(all))
+	for i := range all {
+		ret[i] = all[i].AttrsMap()
+	}
+	return ret
+}
+
+// CountLevel returns the number of observed logs logged at exactly the given level.
+func CountLevel(logs ObservedLogs, level slog.Level) int {
+	return Count(logs, func(r LoggedRecord) bool {
+		return r.Record.Level == level
+	})
+}
+
+// CountMessage returns the number of observed logs that have the specified message.
+func CountMessage(logs ObservedLogs, msg string) int {
+	return Count(logs, func(r LoggedRecord) bool {
+		return r.Record.Message == msg
+	})
+}
+
+// Contains reports whether there is an observed log that has the specified message.
+func Contains(logs ObservedLogs, msg string) bool {
+	return Any(logs, func(r LoggedRecord) bool {
+		return r.Record.Message == msg
+	})
+}
+
+// ContainsSnippet reports whether there is an observed log that has a message containing the specified snippet.
+func ContainsSnippet(logs ObservedLogs, snippet string) bool {
+	return Any(logs, func(r LoggedRecord) bool {
+		return strings.Contains(r.Record.Message, snippet)
+	})
+}
+
+// ContainsAttr reports whether there is an observed log that has the specified attribute,
+// same as ObservedLogs.FilterAttr.
+func ContainsAttr(logs ObservedLogs, attr slog.Attr) bool {
+	return Any(logs, func(r LoggedRecord) bool {
+		return filterAttr(r.Attrs, attr)
+	})
+}
+
+// GroupByMessage returns copies of all the observed logs grouped by message,
+// logs are in the order they were logged within each group.
+func GroupByMessage(logs ObservedLogs) map[string][]LoggedRecord {
+	return groupBy(logs, func(r LoggedRecord) string {
+		return r.Record.Message
+	})
+}
+
+// GroupByLevel returns copies of all the observed logs grouped by level,
+// logs are in the order they were logged within each group.
+func GroupByLevel(logs ObservedLogs) map[slog.Level][]LoggedRecord {
+	return groupBy(logs, func(r LoggedRecord) slog.Level {
+		return r.Record.Level
+	})
+}
+
+// Partition splits the observed logs into two independent collections: entries for which the provided function
+// returns true and the rest. The collection is walked once with Range, see its restrictions for the function,
+// so the function is called once for each entry and records logged in the meantime are not split between the halves.
+func Partition(logs ObservedLogs, keep func(LoggedRecord) bool) (matched, rest ObservedLogs) {
+	var kept, other []LoggedRecord
+	Range(logs, func(r LoggedRecord) bool {
+		if keep(r) {
+			kept = append(kept, r)
+		} else {
+			other = append(other, r)
+		}
+		return true
+	})
+	return derive(logs, kept), derive(logs, other)
+}
+
+// groupBy groups records by the key, preserving records order within each group.
+func groupBy[K comparable](logs ObservedLogs, key func(LoggedRecord) K) map[K][]LoggedRecord {
+	res := make(map[K][]LoggedRecord)
+	for _, r := range logs.All() {
+		k := key(r)
+		res[k] = append(res[k], r)
+	}
+	return res
+}
+
+// Snapshot returns a read-only copy of the observed logs with their current contents, see Snapshotter.
+// Collections that do not implement Snapshotter are copied with All into a read-only ObservedLogsDefault.
+func Snapshot(logs ObservedLogs) ObservedLogs {
+	if s, ok := logs.(Snapshotter); ok {
+		return s.Snapshot()
+	}
+	return newSnapshot(logs.All())
+}
+
+// Clone returns an independent writable copy of the observed logs with their current contents, see Cloner.
+// Collections that do not implement Cloner are copied with All into ObservedLogsDefault of the same Cap.
+func Clone(logs ObservedLogs) ObservedLogs {
+	if c, ok := logs.(Cloner); ok {
+		return c.Clone()
+	}
+
+	c := NewObservedLogsDefault(uint(Cap(logs)))
+	c.AddRecords(logs.All())
+	return c
+}
+
+// waitForPollInterval is the interval WaitFor polls the collections that do not implement Subscriber with.
+const waitForPollInterval = 10 * time.Millisecond
+
+// WaitFor blocks until the record that satisfies the provided function is observed or the context is done.
+// Already observed records are checked first. Returns the matched record and whether it was found.
+// Collections that do not implement Subscriber are polled, all the observed logs are checked each time.
+func WaitFor(ctx context.Context, logs ObservedLogs, keep func(LoggedRecord) bool) (LoggedRecord, bool) {
+	s, ok := logs.(Subscriber)
+	if !ok {
+		return pollFor(ctx, logs, keep)
+	}
+
+	records, ch, cancel := s.SnapshotAndSubscribe()
+	defer cancel()
+
+	for _, r := range records {
+		if keep(r) {
+			return r, true
+		}
+	}
+
+	for {
+		select {
+		case r := <-ch:
+			if keep(r) {
+				return r, true
+			}
+		case <-ctx.Done():
+			return LoggedRecord{}, false
+		}
+	}
+}
+
+func pollFor(ctx context.Context, logs ObservedLogs, keep func(LoggedRecord) bool) (LoggedRecord, bool) {
+	ticker := time.NewTicker(waitForPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, r := range logs.All() {
+			if keep(r) {
+				return r, true
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return LoggedRecord{}, false
+		}
+	}
+}
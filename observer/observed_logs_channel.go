@@ -0,0 +1,157 @@
+package observer
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+var (
+	_ ObservedLogs   = (*ObservedLogsChannel)(nil)
+	_ RecordsAdder   = (*ObservedLogsChannel)(nil)
+	_ Limiter        = (*ObservedLogsChannel)(nil)
+	_ TotalCounter   = (*ObservedLogsChannel)(nil)
+	_ DroppedCounter = (*ObservedLogsChannel)(nil)
+	_ Subscriber     = (*ObservedLogsChannel)(nil)
+)
+
+// ObservedLogsChannel is an implementation of ObservedLogs that does not store records, but forwards them
+// to the channel, e.g. to stream them to a file without growing memory. The collection is always empty:
+// Len returns 0, All returns an empty slice, helpers such as Count or Any never match and derived collections,
+// e.g. filtered ones, are empty ObservedLogsDefault. Subscribers and WaitFor receive forwarded records.
+type ObservedLogsChannel struct {
+	subs subscribers
+
+	ch       chan<- LoggedRecord
+	blocking bool
+	addSeq   atomic.Bool
+	total    atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+// NewObservedLogsChannel creates new ObservedLogsChannel that forwards records to ch.
+// If blocking is true, then logging blocks until the record is received from the channel,
+// otherwise the record is dropped when the channel is not ready to receive it, see Dropped.
+func NewObservedLogsChannel(ch chan<- LoggedRecord, blocking bool) *ObservedLogsChannel {
+	return &ObservedLogsChannel{
+		ch:       ch,
+		blocking: blocking,
+	}
+}
+
+// Add forwards log record to the channel. Expects a record that is already prepared for storing:
+// - has no attributes
+// - attributes collection is passed alongside
+func (o *ObservedLogsChannel) Add(record slog.Record, attrs []slog.Attr) {
+	o.AddRecords([]LoggedRecord{{Record: record, Attrs: attrs}})
+}
+
+// AddRecords forwards log records to the channel in the given order, the same way as Add does.
+func (o *ObservedLogsChannel) AddRecords(records []LoggedRecord) {
+	for _, lr := range records {
+		if seq := o.total.Add(1); o.addSeq.Load() {
+			lr.Seq = seq
+		}
+		o.subs.publish(lr)
+
+		if o.blocking {
+			o.ch <- lr
+			continue
+		}
+
+		select {
+		case o.ch <- lr:
+		default:
+			o.dropped.Add(1)
+		}
+	}
+}
+
+// Len always returns 0 as records are not stored.
+func (o *ObservedLogsChannel) Len() int {
+	return 0
+}
+
+// Cap always returns 0 as records are not stored.
+func (o *ObservedLogsChannel) Cap() int {
+	return 0
+}
+
+// Bounded reports whether the collection drops records, that is the case when the channel is not blocking.
+func (o *ObservedLogsChannel) Bounded() bool {
+	return !o.blocking
+}
+
+// Total returns the number of records ever added to the collection.
+func (o *ObservedLogsChannel) Total() uint64 {
+	return o.total.Load()
+}
+
+// Dropped returns the number of records that were not forwarded because the channel was not ready
+// to receive them or were sampled out, see HandlerOptions.Sample.
+func (o *ObservedLogsChannel) Dropped() uint64 {
+	return o.dropped.Load()
+}
+
+// All always returns an empty slice as records are not stored.
+func (o *ObservedLogsChannel) All() []LoggedRecord {
+	return []LoggedRecord{}
+}
+
+// TakeAll always returns an empty slice as records are not stored.
+func (o *ObservedLogsChannel) TakeAll() []LoggedRecord {
+	return []LoggedRecord{}
+}
+
+// AllUntimed always returns an empty slice as records are not stored.
+func (o *ObservedLogsChannel) AllUntimed() []LoggedRecord {
+	return []LoggedRecord{}
+}
+
+// Subscribe returns a channel that receives all the records forwarded after the subscription
+// and the function that cancels the subscription and closes the channel.
+// Records are delivered in the order they were added, slow subscribers do not block logging.
+func (o *ObservedLogsChannel) Subscribe() (<-chan LoggedRecord, func()) {
+	return o.subs.subscribe()
+}
+
+// SnapshotAndSubscribe returns an empty slice as records are not stored and subscribes for the records
+// forwarded after that, see Subscribe.
+func (o *ObservedLogsChannel) SnapshotAndSubscribe() ([]LoggedRecord, <-chan LoggedRecord, func()) {
+	ch, cancel := o.subs.subscribe()
+	return []LoggedRecord{}, ch, cancel
+}
+
+// Filter returns an empty ObservedLogsDefault as records are not stored, the same applies to all the other
+// methods that derive collections.
+func (o *ObservedLogsChannel) Filter(func(LoggedRecord) bool) ObservedLogs {
+	return NewObservedLogsDefault(0)
+}
+
+// FilterLevelExact returns an empty ObservedLogsDefault, see Filter.
+func (o *ObservedLogsChannel) FilterLevelExact(slog.Level) ObservedLogs {
+	return NewObservedLogsDefault(0)
+}
+
+// FilterMessage returns an empty ObservedLogsDefault, see Filter.
+func (o *ObservedLogsChannel) FilterMessage(string) ObservedLogs {
+	return NewObservedLogsDefault(0)
+}
+
+// FilterMessageSnippet returns an empty ObservedLogsDefault, see Filter.
+func (o *ObservedLogsChannel) FilterMessageSnippet(string) ObservedLogs {
+	return NewObservedLogsDefault(0)
+}
+
+// FilterAttr returns an empty ObservedLogsDefault, see Filter.
+func (o *ObservedLogsChannel) FilterAttr(slog.Attr) ObservedLogs {
+	return NewObservedLogsDefault(0)
+}
+
+// FilterFieldKey returns an empty ObservedLogsDefault, see Filter.
+func (o *ObservedLogsChannel) FilterFieldKey(string) ObservedLogs {
+	return NewObservedLogsDefault(0)
+}
+
+func (o *ObservedLogsChannel) sampledOut() {
+	o.dropped.Add(1)
+}
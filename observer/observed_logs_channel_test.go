@@ -0,0 +1,99 @@
+package observer
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObservedLogsChannel(t *testing.T) {
+	t.Run("blocking", func(t *testing.T) {
+		ch := make(chan LoggedRecord)
+		handler, logs := New(&HandlerOptions{ObservedLogs: NewObservedLogsChannel(ch, true)})
+		logger := slog.New(handler).With(slog.String("component", "db"))
+
+		go func() {
+			for i := 0; i < 3; i++ {
+				logger.Info("log", slog.Int("i", i))
+			}
+			close(ch)
+		}()
+
+		var got []map[string]any
+		for r := range ch {
+			got = append(got, r.AttrsMap())
+		}
+		assert.Equal(t, []map[string]any{
+			{"component": "db", "i": int64(0)},
+			{"component": "db", "i": int64(1)},
+			{"component": "db", "i": int64(2)},
+		}, got)
+
+		assert.Equal(t, 0, logs.Len())
+		assert.Empty(t, logs.All())
+		assert.Equal(t, 0, logs.FilterMessage("log").Len())
+		assert.Equal(t, uint64(3), Total(logs))
+		assert.Equal(t, uint64(0), logs.(DroppedCounter).Dropped())
+		assert.False(t, Bounded(logs))
+		assert.Zero(t, Count(logs, func(LoggedRecord) bool { return true }))
+		assert.False(t, Any(logs, func(LoggedRecord) bool { return true }))
+		assert.True(t, None(logs, func(LoggedRecord) bool { return true }))
+		assert.Empty(t, CountByLevel(logs))
+
+		// derived collections are empty and independent
+		filtered := logs.Filter(func(LoggedRecord) bool { return true })
+		filtered.Add(slog.NewRecord(time.Time{}, slog.LevelInfo, "added", 0), nil)
+		assert.Equal(t, 1, filtered.Len())
+		assert.Equal(t, 0, logs.FilterMessage("added").Len())
+	})
+
+	t.Run("non-blocking", func(t *testing.T) {
+		ch := make(chan LoggedRecord, 2)
+		handler, logs := New(&HandlerOptions{ObservedLogs: NewObservedLogsChannel(ch, false)})
+		logger := slog.New(handler)
+
+		for i := 0; i < 5; i++ {
+			logger.Info("log", slog.Int("i", i))
+		}
+
+		require.Len(t, ch, 2)
+		assert.Equal(t, map[string]any{"i": int64(0)}, (<-ch).AttrsMap())
+		assert.Equal(t, map[string]any{"i": int64(1)}, (<-ch).AttrsMap())
+		assert.Equal(t, uint64(5), Total(logs))
+		assert.Equal(t, uint64(3), logs.(DroppedCounter).Dropped())
+		assert.True(t, Bounded(logs))
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{ObservedLogs: NewObservedLogsChannel(make(chan LoggedRecord, 1), false)})
+		logger := slog.New(handler)
+
+		records, ch, cancel := logs.(Subscriber).SnapshotAndSubscribe()
+		defer cancel()
+		assert.Empty(t, records)
+
+		logger.Info("forwarded")
+		r := <-ch
+		assert.Equal(t, "forwarded", r.Record.Message)
+	})
+
+	t.Run("WaitFor", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{ObservedLogs: NewObservedLogsChannel(make(chan LoggedRecord), false)})
+		logger := slog.New(handler)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			logger.Info("ready")
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		r, ok := WaitFor(ctx, logs, func(r LoggedRecord) bool { return r.Record.Message == "ready" })
+		require.True(t, ok)
+		assert.Equal(t, "ready", r.Record.Message)
+	})
+}
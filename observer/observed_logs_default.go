@@ -3,20 +3,46 @@ package observer
 import (
 	"log/slog"
 	"reflect"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 )
 
-var _ ObservedLogs = (*ObservedLogsDefault)(nil)
+var (
+	_ ObservedLogs   = (*ObservedLogsDefault)(nil)
+	_ RecordsAdder   = (*ObservedLogsDefault)(nil)
+	_ Limiter        = (*ObservedLogsDefault)(nil)
+	_ TotalCounter   = (*ObservedLogsDefault)(nil)
+	_ Truncater      = (*ObservedLogsDefault)(nil)
+	_ Indexer        = (*ObservedLogsDefault)(nil)
+	_ DroppedCounter = (*ObservedLogsDefault)(nil)
+	_ Subscriber     = (*ObservedLogsDefault)(nil)
+	_ Ranger         = (*ObservedLogsDefault)(nil)
+	_ Snapshotter    = (*ObservedLogsDefault)(nil)
+	_ Cloner         = (*ObservedLogsDefault)(nil)
+	_ CursorReader   = (*ObservedLogsDefault)(nil)
+)
 
 // ObservedLogsDefault is a concurrency-safe, ordered implementation of ObservedLogs.
 type ObservedLogsDefault struct {
-	mu sync.RWMutex
+	mu   sync.RWMutex
+	subs subscribers
 
-	fixed bool
-	size  int
-	logs  []LoggedRecord
+	fixed    bool
+	size     int
+	total    int
+	stored   int // the number of records ever stored, positions for Cursor
+	seq      uint64
+	addSeq   bool
+	dedup    bool
+	maxBytes int
+	bytes    int
+	sizes    []int // sizes of the records in logs if maxBytes is set, see LoggedRecord.size
+	logs     []LoggedRecord
+	dropped  uint64
+	onEvict  func(LoggedRecord)
+	frozen   bool
 }
 
 // NewObservedLogsDefault creates and initializes new ObservedLogsDefault.
@@ -37,6 +63,68 @@ func (o *ObservedLogsDefault) Len() int {
 	return n
 }
 
+// Cap returns the maximum number of records the collection holds or 0 if it is unbounded.
+func (o *ObservedLogsDefault) Cap() int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	if !o.fixed {
+		return 0
+	}
+	return cap(o.logs)
+}
+
+// Bounded reports whether the collection has MaxLogs or MaxBytes limit.
+func (o *ObservedLogsDefault) Bounded() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	return o.fixed || o.maxBytes > 0
+}
+
+// Total returns the number of records ever added to the collection, including the evicted, truncated
+// and collapsed ones, see HandlerOptions.Dedup. It is never reset.
+func (o *ObservedLogsDefault) Total() uint64 {
+	o.mu.RLock()
+	n := o.total
+	o.mu.RUnlock()
+	return uint64(n)
+}
+
+// Dropped returns the number of records evicted because of the MaxLogs or MaxBytes limits
+// or sampled out, see HandlerOptions.Sample, since the collection creation.
+func (o *ObservedLogsDefault) Dropped() uint64 {
+	o.mu.RLock()
+	n := o.dropped
+	o.mu.RUnlock()
+	return n
+}
+
+// At returns the observed log at the logical position i, the oldest one has position 0.
+// Negative positions count from the end, e.g. At(-1) returns the latest log.
+// The second return value is false if there is no log at the position.
+func (o *ObservedLogsDefault) At(i int) (LoggedRecord, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	i, ok := logicalIndex(i, len(o.logs))
+	if !ok {
+		return LoggedRecord{}, false
+	}
+	return o.logs[i], true
+}
+
+// Last returns a copy of up to n latest observed logs in the order they were logged.
+func (o *ObservedLogsDefault) Last(n int) []LoggedRecord {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	n = min(max(n, 0), len(o.logs))
+	ret := make([]LoggedRecord, n)
+	copy(ret, o.logs[len(o.logs)-n:])
+	return ret
+}
+
 // All returns a copy of all the observed logs.
 func (o *ObservedLogsDefault) All() []LoggedRecord {
 	o.mu.RLock()
@@ -48,20 +136,145 @@ func (o *ObservedLogsDefault) All() []LoggedRecord {
 
 // TakeAll returns a copy of all the observed logs, and truncates the observed slice.
 func (o *ObservedLogsDefault) TakeAll() []LoggedRecord {
+	checkWritable(o.frozen, "TakeAll")
+
 	o.mu.Lock()
 	ret := o.logs
 	o.logs = nil
+	if o.fixed {
+		o.logs = make([]LoggedRecord, 0, cap(ret))
+	}
+	o.size = 0
+	o.bytes = 0
+	o.sizes = o.sizes[:0]
 	o.mu.Unlock()
 	return ret
 }
 
+// TakeN returns up to n oldest observed logs and removes them from the collection, leaving the rest in place.
+func (o *ObservedLogsDefault) TakeN(n int) []LoggedRecord {
+	checkWritable(o.frozen, "TakeN")
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	n = min(max(n, 0), len(o.logs))
+	ret := make([]LoggedRecord, n)
+	copy(ret, o.logs)
+
+	if o.maxBytes > 0 {
+		for _, size := range o.sizes[:n] {
+			o.bytes -= size
+		}
+	}
+	o.removeFirst(n)
+	return ret
+}
+
+// Reset truncates the observed logs without returning them.
+func (o *ObservedLogsDefault) Reset() {
+	checkWritable(o.frozen, "Reset")
+
+	o.mu.Lock()
+	clear(o.logs)
+	o.logs = o.logs[:0]
+	o.size = 0
+	o.bytes = 0
+	o.sizes = o.sizes[:0]
+	o.mu.Unlock()
+}
+
+// Subscribe returns a channel that receives all the records added to the collection after the subscription
+// and the function that cancels the subscription and closes the channel.
+// Records are delivered in the order they were added, slow subscribers do not block logging.
+func (o *ObservedLogsDefault) Subscribe() (<-chan LoggedRecord, func()) {
+	return o.subs.subscribe()
+}
+
+// SnapshotAndSubscribe atomically returns a copy of all the observed logs and subscribes for the records
+// added after that.
+func (o *ObservedLogsDefault) SnapshotAndSubscribe() ([]LoggedRecord, <-chan LoggedRecord, func()) {
+	// records are published under the write lock, so holding the read lock makes copying and subscribing atomic
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	records := slices.Clone(o.logs)
+	ch, cancel := o.subs.subscribe()
+	return records, ch, cancel
+}
+
+// Cursor returns the current position in the collection that can be used with AllSince.
+func (o *ObservedLogsDefault) Cursor() Cursor {
+	o.mu.RLock()
+	c := Cursor{pos: o.stored}
+	o.mu.RUnlock()
+	return c
+}
+
+// AllSince returns a copy of the observed logs added after the cursor position, the cursor for the current
+// position and the number of records that were added after the cursor position but are not available anymore,
+// e.g. were evicted because of the MaxLogs limit or truncated.
+func (o *ObservedLogsDefault) AllSince(cursor Cursor) ([]LoggedRecord, Cursor, int) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	first := o.stored - len(o.logs)
+	start, dropped := sinceBounds(cursor, first, o.stored)
+
+	ret := make([]LoggedRecord, o.stored-start)
+	copy(ret, o.logs[start-first:])
+	return ret, Cursor{pos: o.stored}, dropped
+}
+
+func (o *ObservedLogsDefault) count(match func(LoggedRecord) bool) int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	var n int
+	for _, r := range o.logs {
+		if match(r) {
+			n++
+		}
+	}
+	return n
+}
+
+func (o *ObservedLogsDefault) contains(match func(LoggedRecord) bool) bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	for _, r := range o.logs {
+		if match(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Range calls fn for each observed log in the order they were logged without copying them.
+// Iteration stops when fn returns false. The read lock is held during the iteration,
+// so fn must not call back into the same collection, otherwise it deadlocks.
+func (o *ObservedLogsDefault) Range(fn func(LoggedRecord) bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	for _, r := range o.logs {
+		if !fn(r) {
+			return
+		}
+	}
+}
+
 // AllUntimed returns a copy of all the observed logs, but overwrites the
-// observed timestamps with time.Time's zero value. This is useful when making
-// assertions in tests.
+// observed timestamps with time.Time's zero value and drops the source code position,
+// see HandlerOptions.AddSource, so LoggedRecord.Source returns nil for them, and the context,
+// see HandlerOptions.AddContext. This is useful when making assertions in tests.
 func (o *ObservedLogsDefault) AllUntimed() []LoggedRecord {
 	ret := o.All()
 	for i := range ret {
 		ret[i].Record.Time = time.Time{}
+		ret[i].Record.PC = 0
+		ret[i].ctx = nil
 	}
 	return ret
 }
@@ -106,6 +319,38 @@ func (o *ObservedLogsDefault) FilterFieldKey(key string) ObservedLogs {
 	})
 }
 
+// Snapshot returns a read-only copy of this ObservedLogsDefault with its current contents.
+func (o *ObservedLogsDefault) Snapshot() ObservedLogs {
+	return newSnapshot(o.All())
+}
+
+// Clone returns an independent writable copy of this ObservedLogsDefault with its current contents,
+// fixed size collections keep their capacity.
+func (o *ObservedLogsDefault) Clone() ObservedLogs {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	c := &ObservedLogsDefault{
+		fixed:    o.fixed,
+		size:     o.size,
+		total:    len(o.logs),
+		stored:   len(o.logs),
+		seq:      o.seq,
+		addSeq:   o.addSeq,
+		dedup:    o.dedup,
+		maxBytes: o.maxBytes,
+		bytes:    o.bytes,
+		sizes:    slices.Clone(o.sizes),
+	}
+	if o.fixed {
+		c.logs = make([]LoggedRecord, len(o.logs), cap(o.logs))
+		copy(c.logs, o.logs)
+	} else {
+		c.logs = slices.Clone(o.logs)
+	}
+	return c
+}
+
 // Filter returns a copy of this ObservedLogsDefault containing only those entries
 // for which the provided function returns true.
 func (o *ObservedLogsDefault) Filter(keep func(LoggedRecord) bool) ObservedLogs {
@@ -118,27 +363,198 @@ func (o *ObservedLogsDefault) Filter(keep func(LoggedRecord) bool) ObservedLogs
 			filtered = append(filtered, entry)
 		}
 	}
-	return &ObservedLogsDefault{logs: filtered}
+	return newDerivedDefault(filtered)
 }
 
 // Add stores log record to the collection. Expects a record that is already prepared for storing:
 // - has no attributes
 // - attributes collection is passed alongside
 func (o *ObservedLogsDefault) Add(record slog.Record, attrs []slog.Attr) {
+	checkWritable(o.frozen, "Add")
+	o.AddRecords([]LoggedRecord{{Record: record, Attrs: attrs}})
+}
+
+// AddRecords stores log records to the collection in the given order, the same way as Add does.
+func (o *ObservedLogsDefault) AddRecords(records []LoggedRecord) {
+	checkWritable(o.frozen, "AddRecords")
+
+	var sizes []int
+	if o.maxBytes > 0 {
+		// estimate sizes outside the lock, as formatting the values may log to the same collection
+		sizes = make([]int, len(records))
+		for i, lr := range records {
+			sizes[i] = lr.size()
+		}
+	}
+
+	var evicted []LoggedRecord
+
 	o.mu.Lock()
+	for i, lr := range records {
+		var size int
+		if sizes != nil {
+			size = sizes[i]
+		}
+		evicted = o.add(lr, size, evicted)
+	}
+	onEvict := o.onEvict
+	o.mu.Unlock()
+
+	for _, r := range evicted {
+		onEvict(r)
+	}
+}
+
+// add stores the record of the given size and appends evicted records to the list if OnEvict is set.
+// Expects the lock to be held by the caller.
+func (o *ObservedLogsDefault) add(lr LoggedRecord, size int, evicted []LoggedRecord) []LoggedRecord {
+	if o.dedup && len(o.logs) > 0 && sameRecord(o.logs[len(o.logs)-1], lr) {
+		o.logs[len(o.logs)-1].Repeated++
+		o.total++
+		return evicted
+	}
+
 	o.size++
+	o.total++
+	o.stored++
+	o.seq++
+	if o.addSeq {
+		lr.Seq = o.seq
+	}
+	if o.maxBytes > 0 {
+		o.bytes += size
+		o.sizes = append(o.sizes, size)
+	}
 	if o.fixed && o.size > cap(o.logs) {
+		if o.onEvict != nil {
+			evicted = append(evicted, o.logs[0])
+		}
+		if o.maxBytes > 0 {
+			o.bytes -= o.sizes[0]
+			o.sizes = o.sizes[:copy(o.sizes, o.sizes[1:])]
+		}
+		o.dropped++
 		copy(o.logs[0:], o.logs[1:])
 		o.size--
-		o.logs[o.size-1] = LoggedRecord{Record: record, Attrs: attrs}
+		o.logs[o.size-1] = lr
 	} else {
-		o.logs = append(o.logs, LoggedRecord{Record: record, Attrs: attrs})
+		o.logs = append(o.logs, lr)
 	}
+	evicted = o.evictBytes(evicted)
+	o.subs.publish(lr)
+	return evicted
+}
+
+func (o *ObservedLogsDefault) setAddSeq(addSeq bool) {
+	o.mu.Lock()
+	o.addSeq = addSeq
+	o.mu.Unlock()
+}
+
+func (o *ObservedLogsDefault) sampledOut() {
+	o.mu.Lock()
+	o.dropped++
+	o.mu.Unlock()
+}
+
+func (o *ObservedLogsDefault) setDedup(dedup bool) {
+	o.mu.Lock()
+	o.dedup = dedup
 	o.mu.Unlock()
 }
 
+func (o *ObservedLogsDefault) setOnEvict(fn func(LoggedRecord)) {
+	o.mu.Lock()
+	o.onEvict = fn
+	o.mu.Unlock()
+}
+
+// evictBytes evicts the oldest records while the size of the stored records exceeds MaxBytes,
+// the latest record is always kept. Evicted records are appended to the provided slice if OnEvict is set.
+// Expects the lock to be held by the caller.
+func (o *ObservedLogsDefault) evictBytes(evicted []LoggedRecord) []LoggedRecord {
+	if o.maxBytes == 0 {
+		return evicted
+	}
+
+	var n int
+	for o.bytes > o.maxBytes && n < len(o.logs)-1 {
+		o.bytes -= o.sizes[n]
+		n++
+	}
+	if o.onEvict != nil {
+		evicted = append(evicted, o.logs[:n]...)
+	}
+	o.dropped += uint64(n)
+	o.removeFirst(n)
+	return evicted
+}
+
+// removeFirst removes the n oldest records by shifting the rest in place and clearing the tail,
+// so that removed records are not kept alive by the backing array. Expects the lock to be held by the caller.
+func (o *ObservedLogsDefault) removeFirst(n int) {
+	if n == 0 {
+		return
+	}
+
+	if o.maxBytes > 0 {
+		o.sizes = o.sizes[:copy(o.sizes, o.sizes[n:])]
+	}
+
+	rest := copy(o.logs, o.logs[n:])
+	clear(o.logs[rest:])
+	o.logs = o.logs[:rest]
+	o.size = rest
+}
+
+// newSnapshot creates read-only ObservedLogsDefault holding the records.
+func newSnapshot(records []LoggedRecord) *ObservedLogsDefault {
+	s := newDerivedDefault(records)
+	s.frozen = true
+	return s
+}
+
+// newDerivedDefault creates an unbounded ObservedLogsDefault that holds the provided logs,
+// it is used for derived collections, e.g. filtered ones.
+func newDerivedDefault(logs []LoggedRecord) *ObservedLogsDefault {
+	return &ObservedLogsDefault{logs: logs, size: len(logs), total: len(logs), stored: len(logs)}
+}
+
+// sinceBounds returns the position to start reading from and the number of dropped records
+// for the collection that holds records in [first, total) positions range.
+func sinceBounds(cursor Cursor, first, total int) (start, dropped int) {
+	start = cursor.pos
+	if start < first {
+		dropped = first - start
+		start = first
+	}
+	if start > total {
+		start = total
+	}
+	return start, dropped
+}
+
+// inTimeRange reports whether t is within the [start, end] range, zero bounds are open-ended.
+func inTimeRange(t, start, end time.Time) bool {
+	if !start.IsZero() && t.Before(start) {
+		return false
+	}
+	return end.IsZero() || !t.After(end)
+}
+
+// checkWritable panics if the collection is a read-only snapshot.
+func checkWritable(frozen bool, method string) {
+	if frozen {
+		panic("observer: " + method + " called on a read-only snapshot")
+	}
+}
+
 func filterAttr(attrs []slog.Attr, attr slog.Attr) bool {
-	for _, a := range attrs {
+	for i, a := range attrs {
+		if shadowed(attrs, i) {
+			continue
+		}
+
 		kind := a.Value.Kind()
 		if kind == slog.KindGroup {
 			if filterAttr(a.Value.Group(), attr) {
@@ -163,3 +579,184 @@ func filterAttr(attrs []slog.Attr, attr slog.Attr) bool {
 	}
 	return false
 }
+
+// sameRecord reports whether the records have the same level, message and attributes, time is ignored.
+// Attributes are compared in place, without building attribute maps, as it is called under the write lock
+// for every added record.
+func sameRecord(a, b LoggedRecord) bool {
+	return a.Record.Level == b.Record.Level && a.Record.Message == b.Record.Message && sameAttrs(a.Attrs, b.Attrs)
+}
+
+// sameAttrs reports whether the attributes have the same keys and values in the same order.
+func sameAttrs(a, b []slog.Attr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Key != b[i].Key || a[i].Value.Kind() != b[i].Value.Kind() {
+			return false
+		}
+
+		switch a[i].Value.Kind() {
+		case slog.KindGroup:
+			if !sameAttrs(a[i].Value.Group(), b[i].Value.Group()) {
+				return false
+			}
+		case slog.KindAny, slog.KindLogValuer:
+			// compare using reflect to avoid panicking when comparing complex types
+			if !reflect.DeepEqual(a[i].Value.Any(), b[i].Value.Any()) {
+				return false
+			}
+		default:
+			if !a[i].Value.Equal(b[i].Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// distinct returns a filter function that keeps only the first occurrence of each unique record.
+func distinct() func(LoggedRecord) bool {
+	type key struct {
+		level slog.Level
+		msg   string
+	}
+
+	seen := make(map[key][]map[string]any)
+	return func(r LoggedRecord) bool {
+		k := key{level: r.Record.Level, msg: r.Record.Message}
+		attrs := r.AttrsMap()
+		for _, s := range seen[k] {
+			// compare using reflect to avoid panicking when comparing complex types
+			if reflect.DeepEqual(s, attrs) {
+				return false
+			}
+		}
+		seen[k] = append(seen[k], attrs)
+		return true
+	}
+}
+
+// filterAttrInGroup navigates into the groups with the specified path and applies filterAttr
+// to the group members, groups with empty keys are inlined on every level.
+func filterAttrInGroup(attrs []slog.Attr, groupPath []string, attr slog.Attr) bool {
+	if len(groupPath) == 0 {
+		return filterAttr(attrs, attr)
+	}
+
+	for i, a := range attrs {
+		if a.Value.Kind() != slog.KindGroup || shadowed(attrs, i) {
+			continue
+		}
+
+		if a.Key == "" {
+			if filterAttrInGroup(a.Value.Group(), groupPath, attr) {
+				return true
+			}
+			continue
+		}
+
+		if a.Key == groupPath[0] && filterAttrInGroup(a.Value.Group(), groupPath[1:], attr) {
+			return true
+		}
+	}
+	return false
+}
+
+func filterAttrValue(attrs []slog.Attr, key string, keep func(slog.Value) bool) bool {
+	for i, a := range attrs {
+		if shadowed(attrs, i) {
+			continue
+		}
+
+		if a.Value.Kind() == slog.KindGroup {
+			if filterAttrValue(a.Value.Group(), key, keep) {
+				return true
+			}
+			continue
+		}
+
+		if a.Key == key && keep(a.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFieldKeyDeep(attrs []slog.Attr, key string) bool {
+	for _, a := range attrs {
+		if a.Key == key {
+			return true
+		}
+
+		if a.Value.Kind() == slog.KindGroup && hasFieldKeyDeep(a.Value.Group(), key) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAttrKeyPrefix reports whether there is an attribute which full key, i.e. the path of the parent groups
+// and the key joined with dots, starts with the prefix. Members of the groups with empty key are inlined.
+func hasAttrKeyPrefix(attrs []slog.Attr, path, prefix string) bool {
+	for _, a := range attrs {
+		if a.Value.Kind() == slog.KindGroup {
+			groupPath := path
+			if a.Key != "" {
+				groupPath = path + a.Key + "."
+			}
+			if hasAttrKeyPrefix(a.Value.Group(), groupPath, prefix) {
+				return true
+			}
+			continue
+		}
+
+		if a.Key != "" && strings.HasPrefix(path+a.Key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func filterGroup(attrs []slog.Attr, name string, deep bool) bool {
+	for i, a := range attrs {
+		if a.Value.Kind() != slog.KindGroup || shadowed(attrs, i) {
+			continue
+		}
+
+		if a.Key == name {
+			return true
+		}
+
+		if deep && filterGroup(a.Value.Group(), name, true) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAttrs(attrs []slog.Attr) bool {
+	for _, a := range attrs {
+		if !isEmptyAttr(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// shadowed reports whether the attribute at position i is overwritten by the later attribute with the same key
+// on the same level, so it is not visible in the rendered output, e.g. when decoding slog.JSONHandler output.
+func shadowed(attrs []slog.Attr, i int) bool {
+	if attrs[i].Key == "" {
+		return false
+	}
+
+	for _, a := range attrs[i+1:] {
+		if a.Key == attrs[i].Key {
+			return true
+		}
+	}
+	return false
+}
@@ -7,16 +7,39 @@ import (
 	"time"
 )
 
-var _ ObservedLogs = (*ObservedLogsRing)(nil)
+var (
+	_ ObservedLogs   = (*ObservedLogsRing)(nil)
+	_ RecordsAdder   = (*ObservedLogsRing)(nil)
+	_ Limiter        = (*ObservedLogsRing)(nil)
+	_ TotalCounter   = (*ObservedLogsRing)(nil)
+	_ Truncater      = (*ObservedLogsRing)(nil)
+	_ Indexer        = (*ObservedLogsRing)(nil)
+	_ DroppedCounter = (*ObservedLogsRing)(nil)
+	_ Subscriber     = (*ObservedLogsRing)(nil)
+	_ Ranger         = (*ObservedLogsRing)(nil)
+	_ Snapshotter    = (*ObservedLogsRing)(nil)
+	_ Cloner         = (*ObservedLogsRing)(nil)
+	_ CursorReader   = (*ObservedLogsRing)(nil)
+)
 
 // ObservedLogsRing is a concurrency-safe, ring buffer implementation of ObservedLogs.
 type ObservedLogsRing struct {
-	mu sync.RWMutex
+	mu   sync.RWMutex
+	subs subscribers
+
+	fixed  bool
+	size   int
+	total  int
+	stored int // the number of records ever stored, positions for Cursor
+	seq    uint64
+	addSeq bool
+	dedup  bool
+	over   bool
+	logs   []LoggedRecord
 
-	fixed bool
-	size  int
-	over  bool
-	logs  []LoggedRecord
+	dropped uint64
+	onEvict func(LoggedRecord)
+	frozen  bool
 }
 
 // NewObservedLogsRing creates and initializes new ObservedLogsRing.
@@ -29,6 +52,13 @@ func NewObservedLogsRing(maxLogs uint) *ObservedLogsRing {
 	return &ol
 }
 
+// newLinearRing creates an unbounded ObservedLogsRing that holds the provided logs in the logical order,
+// it is used for derived collections, e.g. filtered ones, so that they do not inherit the capacity
+// and the wrapping position of the parent and behave the same way regardless of the parent being wrapped.
+func newLinearRing(logs []LoggedRecord) *ObservedLogsRing {
+	return &ObservedLogsRing{logs: logs, size: len(logs), total: len(logs), stored: len(logs)}
+}
+
 // Len returns the number of items in the collection.
 func (o *ObservedLogsRing) Len() int {
 	o.mu.RLock()
@@ -46,6 +76,77 @@ func (o *ObservedLogsRing) len() (n int) {
 	return
 }
 
+// Cap returns the maximum number of records the collection holds or 0 if it is unbounded.
+func (o *ObservedLogsRing) Cap() int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	if !o.fixed {
+		return 0
+	}
+	return cap(o.logs)
+}
+
+// Bounded reports whether the collection has fixed size.
+func (o *ObservedLogsRing) Bounded() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	return o.fixed
+}
+
+// Total returns the number of records ever added to the collection, including the evicted, truncated
+// and collapsed ones, see HandlerOptions.Dedup. It is never reset.
+func (o *ObservedLogsRing) Total() uint64 {
+	o.mu.RLock()
+	n := o.total
+	o.mu.RUnlock()
+	return uint64(n)
+}
+
+// Dropped returns the number of records overwritten by the fixed size ring or sampled out,
+// see HandlerOptions.Sample, since the collection creation.
+func (o *ObservedLogsRing) Dropped() uint64 {
+	o.mu.RLock()
+	n := o.dropped
+	o.mu.RUnlock()
+	return n
+}
+
+// At returns the observed log at the logical position i, the oldest one has position 0.
+// Negative positions count from the end, e.g. At(-1) returns the latest log.
+// The second return value is false if there is no log at the position.
+func (o *ObservedLogsRing) At(i int) (LoggedRecord, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	i, ok := logicalIndex(i, o.len())
+	if !ok {
+		return LoggedRecord{}, false
+	}
+	if o.fixed && o.over {
+		i = (o.size + i) % cap(o.logs)
+	}
+	return o.logs[i], true
+}
+
+// Last returns a copy of up to n latest observed logs in the order they were logged.
+func (o *ObservedLogsRing) Last(n int) []LoggedRecord {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	total := o.len()
+	n = min(max(n, 0), total)
+	ret := make([]LoggedRecord, 0, n)
+	o.each(func(i int, r LoggedRecord) bool {
+		if i >= total-n {
+			ret = append(ret, r)
+		}
+		return true
+	})
+	return ret
+}
+
 // All returns a copy of all the observed logs.
 func (o *ObservedLogsRing) All() []LoggedRecord {
 	o.mu.RLock()
@@ -56,8 +157,8 @@ func (o *ObservedLogsRing) All() []LoggedRecord {
 
 func (o *ObservedLogsRing) all() []LoggedRecord {
 	ret := make([]LoggedRecord, o.len())
-	if !o.fixed {
-		copy(ret, o.logs)
+	if !o.fixed || !o.over {
+		copy(ret, o.logs[:o.size])
 	} else {
 		copy(ret, o.logs[o.size%cap(o.logs):])
 		copy(ret[cap(o.logs)-o.size%cap(o.logs):], o.logs[:o.size%cap(o.logs)])
@@ -67,26 +168,144 @@ func (o *ObservedLogsRing) all() []LoggedRecord {
 
 // TakeAll returns a copy of all the observed logs, and truncates the observed slice.
 func (o *ObservedLogsRing) TakeAll() []LoggedRecord {
+	checkWritable(o.frozen, "TakeAll")
+
 	o.mu.Lock()
 	ret := o.all()
+	o.reset()
+	o.mu.Unlock()
+	return ret
+}
+
+// TakeN returns up to n oldest observed logs and removes them from the collection, leaving the rest in place.
+func (o *ObservedLogsRing) TakeN(n int) []LoggedRecord {
+	checkWritable(o.frozen, "TakeN")
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	all := o.all()
+	n = min(max(n, 0), len(all))
+
+	// linearize the rest, so that the oldest remaining record becomes the logical start
+	o.reset()
+	if !o.fixed {
+		o.logs = append(o.logs, all[n:]...)
+	} else {
+		copy(o.logs, all[n:])
+	}
+	o.size = len(all) - n
+	return all[:n:n]
+}
+
+// Reset truncates the observed logs without returning them.
+func (o *ObservedLogsRing) Reset() {
+	checkWritable(o.frozen, "Reset")
+
+	o.mu.Lock()
+	o.reset()
+	o.mu.Unlock()
+}
+
+func (o *ObservedLogsRing) reset() {
 	o.size = 0
 	o.over = false
 	if !o.fixed {
 		o.logs = nil
 	} else {
-		o.logs = make([]LoggedRecord, cap(o.logs))
+		clear(o.logs)
 	}
-	o.mu.Unlock()
-	return ret
+}
+
+// Subscribe returns a channel that receives all the records added to the collection after the subscription
+// and the function that cancels the subscription and closes the channel.
+// Records are delivered in the order they were added, slow subscribers do not block logging.
+func (o *ObservedLogsRing) Subscribe() (<-chan LoggedRecord, func()) {
+	return o.subs.subscribe()
+}
+
+// SnapshotAndSubscribe atomically returns a copy of all the observed logs and subscribes for the records
+// added after that.
+func (o *ObservedLogsRing) SnapshotAndSubscribe() ([]LoggedRecord, <-chan LoggedRecord, func()) {
+	// records are published under the write lock, so holding the read lock makes copying and subscribing atomic
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	records := o.all()
+	ch, cancel := o.subs.subscribe()
+	return records, ch, cancel
+}
+
+// Cursor returns the current position in the collection that can be used with AllSince.
+func (o *ObservedLogsRing) Cursor() Cursor {
+	o.mu.RLock()
+	c := Cursor{pos: o.stored}
+	o.mu.RUnlock()
+	return c
+}
+
+// AllSince returns a copy of the observed logs added after the cursor position, the cursor for the current
+// position and the number of records that were added after the cursor position but are not available anymore,
+// e.g. were evicted because of the MaxLogs limit or truncated.
+func (o *ObservedLogsRing) AllSince(cursor Cursor) ([]LoggedRecord, Cursor, int) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	all := o.all()
+	first := o.stored - len(all)
+	start, dropped := sinceBounds(cursor, first, o.stored)
+
+	return all[start-first:], Cursor{pos: o.stored}, dropped
+}
+
+func (o *ObservedLogsRing) count(match func(LoggedRecord) bool) int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	var n int
+	o.each(func(_ int, r LoggedRecord) bool {
+		if match(r) {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+func (o *ObservedLogsRing) contains(match func(LoggedRecord) bool) bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	var found bool
+	o.each(func(_ int, r LoggedRecord) bool {
+		found = match(r)
+		return !found
+	})
+	return found
+}
+
+// Range calls fn for each observed log in the order they were logged without copying them.
+// Iteration stops when fn returns false. The read lock is held during the iteration,
+// so fn must not call back into the same collection, otherwise it deadlocks.
+func (o *ObservedLogsRing) Range(fn func(LoggedRecord) bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	o.each(func(_ int, r LoggedRecord) bool {
+		return fn(r)
+	})
 }
 
 // AllUntimed returns a copy of all the observed logs, but overwrites the
-// observed timestamps with time.Time's zero value. This is useful when making
-// assertions in tests.
+// observed timestamps with time.Time's zero value and drops the source code position,
+// see HandlerOptions.AddSource, so LoggedRecord.Source returns nil for them, and the context,
+// see HandlerOptions.AddContext. This is useful when making assertions in tests.
 func (o *ObservedLogsRing) AllUntimed() []LoggedRecord {
 	ret := o.All()
 	for i := range ret {
 		ret[i].Record.Time = time.Time{}
+		ret[i].Record.PC = 0
+		ret[i].ctx = nil
 	}
 	return ret
 }
@@ -131,47 +350,157 @@ func (o *ObservedLogsRing) FilterFieldKey(key string) ObservedLogs {
 	})
 }
 
-// Filter returns a copy of this ObservedLogsDefault containing only those entries
-// for which the provided function returns true.
+// Snapshot returns a read-only copy of this ObservedLogsRing with its current contents.
+func (o *ObservedLogsRing) Snapshot() ObservedLogs {
+	s := newLinearRing(o.All())
+	s.frozen = true
+	return s
+}
+
+// Clone returns an independent writable copy of this ObservedLogsRing with its current contents,
+// fixed size collections keep their capacity. The copy holds the entries in the logical order.
+func (o *ObservedLogsRing) Clone() ObservedLogs {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	all := o.all()
+	if !o.fixed {
+		c := newLinearRing(all)
+		c.seq, c.addSeq, c.dedup = o.seq, o.addSeq, o.dedup
+		return c
+	}
+
+	c := &ObservedLogsRing{fixed: true, logs: make([]LoggedRecord, cap(o.logs)), size: len(all), total: len(all), stored: len(all), seq: o.seq, addSeq: o.addSeq, dedup: o.dedup}
+	copy(c.logs, all)
+	return c
+}
+
+// Filter returns a copy of this ObservedLogsRing containing only those entries
+// for which the provided function returns true. The copy is an unbounded ring
+// with the entries already in the logical order, see newLinearRing.
 func (o *ObservedLogsRing) Filter(keep func(LoggedRecord) bool) ObservedLogs {
 	o.mu.RLock()
 	defer o.mu.RUnlock()
 
 	var filtered []LoggedRecord
-	if !o.fixed {
-		for _, entry := range o.logs {
-			if keep(entry) {
-				filtered = append(filtered, entry)
-			}
+	o.each(func(_ int, entry LoggedRecord) bool {
+		if keep(entry) {
+			filtered = append(filtered, entry)
 		}
-	} else {
-		for _, entry := range o.logs[o.size%cap(o.logs):] {
-			if keep(entry) {
-				filtered = append(filtered, entry)
+		return true
+	})
+	return newLinearRing(filtered)
+}
+
+// each calls the function for every live entry in the logical order, the oldest entry has position 0.
+// Iteration stops when the function returns false. Expects the lock to be held by the caller.
+func (o *ObservedLogsRing) each(fn func(i int, r LoggedRecord) bool) {
+	if !o.fixed || !o.over {
+		for i, entry := range o.logs[:o.size] {
+			if !fn(i, entry) {
+				return
 			}
 		}
-		for _, entry := range o.logs[:o.size%cap(o.logs)] {
-			if keep(entry) {
-				filtered = append(filtered, entry)
-			}
+		return
+	}
+
+	head := o.size % cap(o.logs)
+	for i, entry := range o.logs[head:] {
+		if !fn(i, entry) {
+			return
+		}
+	}
+	for i, entry := range o.logs[:head] {
+		if !fn(cap(o.logs)-head+i, entry) {
+			return
 		}
 	}
-	return &ObservedLogsRing{logs: filtered, size: len(filtered)}
 }
 
 // Add stores log record to the collection. Expects a record that is already prepared for storing:
 // - has no attributes
 // - attributes collection is passed alongside
 func (o *ObservedLogsRing) Add(record slog.Record, attrs []slog.Attr) {
+	checkWritable(o.frozen, "Add")
+	o.AddRecords([]LoggedRecord{{Record: record, Attrs: attrs}})
+}
+
+// AddRecords stores log records to the collection in the given order, the same way as Add does.
+func (o *ObservedLogsRing) AddRecords(records []LoggedRecord) {
+	checkWritable(o.frozen, "AddRecords")
+
+	var evicted []LoggedRecord
+
 	o.mu.Lock()
+	for _, lr := range records {
+		if r, ok := o.add(lr); ok && o.onEvict != nil {
+			evicted = append(evicted, r)
+		}
+	}
+	onEvict := o.onEvict
+	o.mu.Unlock()
+
+	for _, r := range evicted {
+		onEvict(r)
+	}
+}
+
+// add stores the record and returns the overwritten one, if any. Expects the lock to be held by the caller.
+func (o *ObservedLogsRing) add(lr LoggedRecord) (evicted LoggedRecord, isEvicted bool) {
+	if o.dedup && o.size > 0 {
+		last := o.size - 1
+		if o.fixed {
+			last %= cap(o.logs)
+		}
+		if sameRecord(o.logs[last], lr) {
+			o.logs[last].Repeated++
+			o.total++
+			return evicted, false
+		}
+	}
+
 	o.size++
+	o.total++
+	o.stored++
+	o.seq++
+	if o.addSeq {
+		lr.Seq = o.seq
+	}
 	if !o.fixed {
-		o.logs = append(o.logs, LoggedRecord{Record: record, Attrs: attrs})
+		o.logs = append(o.logs, lr)
 	} else {
 		idx := (o.size - 1) % cap(o.logs)
-		o.logs[idx].Record = record
-		o.logs[idx].Attrs = attrs
 		o.over = o.size > cap(o.logs)
+		if o.over {
+			evicted, isEvicted = o.logs[idx], true
+			o.dropped++
+		}
+		o.logs[idx] = lr
 	}
+	o.subs.publish(lr)
+	return evicted, isEvicted
+}
+
+func (o *ObservedLogsRing) setAddSeq(addSeq bool) {
+	o.mu.Lock()
+	o.addSeq = addSeq
+	o.mu.Unlock()
+}
+
+func (o *ObservedLogsRing) sampledOut() {
+	o.mu.Lock()
+	o.dropped++
+	o.mu.Unlock()
+}
+
+func (o *ObservedLogsRing) setDedup(dedup bool) {
+	o.mu.Lock()
+	o.dedup = dedup
+	o.mu.Unlock()
+}
+
+func (o *ObservedLogsRing) setOnEvict(fn func(LoggedRecord)) {
+	o.mu.Lock()
+	o.onEvict = fn
 	o.mu.Unlock()
 }
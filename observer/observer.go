@@ -3,9 +3,14 @@ package observer
 import (
 	"context"
 	"log/slog"
+	"slices"
+	"time"
 )
 
-// ObservedLogs is a collection of observed logs.
+// ObservedLogs is a collection of observed logs. The interface holds the core methods only, the rest of the helpers,
+// e.g. Count, FilterLevels or Messages, are package functions that work with any collection.
+// Collections may implement optional interfaces, e.g. Ranger, Subscriber or CursorReader, that the helpers
+// use when available, falling back to the core methods otherwise.
 type ObservedLogs interface {
 	Add(record slog.Record, attrs []slog.Attr)
 	// Len returns the number of items in the collection.
@@ -15,8 +20,9 @@ type ObservedLogs interface {
 	// TakeAll returns a copy of all the observed logs, and truncates the observed slice.
 	TakeAll() []LoggedRecord
 	// AllUntimed returns a copy of all the observed logs, but overwrites the
-	// observed timestamps with time.Time's zero value. This is useful when making
-	// assertions in tests.
+	// observed timestamps with time.Time's zero value and drops the source code position,
+	// see HandlerOptions.AddSource, so LoggedRecord.Source returns nil for them, and the context,
+	// see HandlerOptions.AddContext. This is useful when making assertions in tests.
 	AllUntimed() []LoggedRecord
 	// Filter returns a copy of this ObservedLogsDefault containing only those entries
 	// for which the provided function returns true.
@@ -33,6 +39,115 @@ type ObservedLogs interface {
 	FilterFieldKey(key string) ObservedLogs
 }
 
+// RecordsAdder is implemented by the ObservedLogs collections that can store already prepared records, see AddRecords.
+type RecordsAdder interface {
+	// AddRecords stores already prepared records in the given order, the same way as Add does,
+	// e.g. to seed the collection with the fixture records without going through the handler.
+	AddRecords(records []LoggedRecord)
+}
+
+// Limiter is implemented by the ObservedLogs collections that can have limits on the records they hold,
+// see Cap and Bounded.
+type Limiter interface {
+	// Cap returns the maximum number of records the collection holds, e.g. MaxLogs, or 0 if it is unbounded.
+	Cap() int
+	// Bounded reports whether the collection drops records because of its limits, e.g. MaxLogs or MaxBytes.
+	Bounded() bool
+}
+
+// TotalCounter is implemented by the ObservedLogs collections that count all the records ever added, see Total.
+type TotalCounter interface {
+	// Total returns the number of records ever added to the collection, including the evicted, truncated
+	// and collapsed ones, see HandlerOptions.Dedup. It is never reset, derived collections, e.g. filtered ones,
+	// start counting from the number of records they were created with.
+	Total() uint64
+}
+
+// Truncater is implemented by the ObservedLogs collections that can remove records without taking all of them,
+// see TakeN and Reset.
+type Truncater interface {
+	// TakeN returns up to n oldest observed logs and removes them from the collection, leaving the rest in place.
+	TakeN(n int) []LoggedRecord
+	// Reset truncates the observed logs without returning them, it is cheaper than TakeAll when the logs
+	// are not needed. Removed records are released, fixed size collections keep their capacity.
+	Reset()
+}
+
+// Indexer is implemented by the ObservedLogs collections that can access the observed logs by position
+// without copying all of them, see At and Last.
+type Indexer interface {
+	// At returns the observed log at the logical position i, the oldest one has position 0.
+	// Negative positions count from the end, e.g. At(-1) returns the latest log.
+	// The second return value is false if there is no log at the position.
+	At(i int) (LoggedRecord, bool)
+	// Last returns a copy of up to n latest observed logs in the order they were logged.
+	Last(n int) []LoggedRecord
+}
+
+// DroppedCounter is implemented by the ObservedLogs collections that can drop records because of their limits,
+// e.g. MaxLogs, or sampling, so that callers holding ObservedLogs can type-assert and check whether records were discarded.
+type DroppedCounter interface {
+	// Dropped returns the number of records evicted, overwritten or sampled out since the collection creation.
+	Dropped() uint64
+}
+
+// Subscriber is implemented by the ObservedLogs collections that notify about the added records,
+// e.g. to follow the logs live or to wait for the record, see WaitFor.
+type Subscriber interface {
+	// Subscribe returns a channel that receives all the records added to the collection after the subscription
+	// and the function that cancels the subscription and closes the channel.
+	// Records are delivered in the order they were added, slow subscribers do not block logging.
+	// Records are queued for the slow subscribers without limit, unless HandlerOptions.SubscriberBuffer is set,
+	// then the oldest queued records are dropped.
+	Subscribe() (<-chan LoggedRecord, func())
+	// SnapshotAndSubscribe atomically returns a copy of all the observed logs and subscribes for the records
+	// added after that, so that no record is missed or received twice in between, see Subscribe.
+	SnapshotAndSubscribe() ([]LoggedRecord, <-chan LoggedRecord, func())
+}
+
+// Ranger is implemented by the ObservedLogs collections that can walk the observed logs without copying them.
+type Ranger interface {
+	// Range calls fn for each observed log in the order they were logged without copying them.
+	// Iteration stops when fn returns false. The read lock is held during the iteration,
+	// so fn must not call back into the same collection, otherwise it deadlocks.
+	Range(fn func(LoggedRecord) bool)
+}
+
+// Snapshotter is implemented by the ObservedLogs collections that can take a consistent read-only copy
+// of their contents, see Snapshot.
+type Snapshotter interface {
+	// Snapshot returns a read-only copy of this ObservedLogs with its current contents, it is not affected
+	// by the records logged or evicted afterward. Add, AddRecords, TakeAll, TakeN and Reset panic on the snapshot,
+	// collections derived from it, e.g. filtered ones, are writable.
+	Snapshot() ObservedLogs
+}
+
+// Cloner is implemented by the ObservedLogs collections that can copy their contents along with the settings,
+// see Clone.
+type Cloner interface {
+	// Clone returns an independent writable copy of this ObservedLogs with its current contents,
+	// it is not affected by the records logged or evicted afterward and keeps the MaxLogs capacity
+	// of the original collection. Subscribers and OnEvict callback are not copied.
+	Clone() ObservedLogs
+}
+
+// CursorReader is implemented by the ObservedLogs collections that track positions of the observed logs,
+// so that only the logs added after a certain point can be read.
+type CursorReader interface {
+	// Cursor returns the current position in the collection that can be used with AllSince.
+	Cursor() Cursor
+	// AllSince returns a copy of the observed logs added after the cursor position, the cursor for the current
+	// position and the number of records that were added after the cursor position but are not available anymore,
+	// e.g. were evicted because of the MaxLogs limit or truncated.
+	AllSince(cursor Cursor) ([]LoggedRecord, Cursor, int)
+}
+
+// Cursor is an opaque position in the ObservedLogs collection, see CursorReader.
+// Zero value points to the beginning of the collection.
+type Cursor struct {
+	pos int
+}
+
 // HandlerOptions are options for an observer Handler.
 type HandlerOptions struct {
 	// Level reports the minimum record level that will be logged.
@@ -42,13 +157,99 @@ type HandlerOptions struct {
 	// to adjust the minimum level dynamically, use a LevelVar.
 	Level slog.Leveler
 
+	// AddSource causes the handler to keep the source code position of the log statement,
+	// that is available with LoggedRecord.Source.
+	AddSource bool
+
+	// ReplaceAttr is called to rewrite each non-group attribute before it is stored, the same way as
+	// slog.HandlerOptions.ReplaceAttr does. The attribute is dropped if ReplaceAttr returns empty attribute.
+	// Unlike slog built-in handlers, built-in attributes (time, level, message and source) are not passed
+	// to ReplaceAttr as they are stored in the LoggedRecord.Record.
+	ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
+
+	// Now is the time source for the records. If set, it overrides the record time,
+	// otherwise the original record time is kept.
+	Now func() time.Time
+
+	// Next is the handler that records are forwarded to after they are stored, so that the observer
+	// becomes a tap rather than a sink, e.g. to see logs in the test output. Next receives original records
+	// and follows WithAttrs and WithGroup calls. Records are forwarded only if Next is enabled for them.
+	Next slog.Handler
+
+	// ContextExtractors are called with the context passed to Handle, the attributes they return
+	// are appended to the record attributes, e.g. to capture request or trace IDs stored in the context
+	// the same way production handlers do. Nil extractors are skipped.
+	ContextExtractors []func(context.Context) []slog.Attr
+
+	// KeepLogValuers makes the handler store slog.LogValuer attribute values as is, without resolving them
+	// the way slog built-in handlers do, e.g. to assert the valuer itself. Values are resolved by default.
+	// ReplaceAttr is always called with the resolved values.
+	KeepLogValuers bool
+
+	// CopyValues makes the handler deep copy slog.KindAny attribute values, e.g. maps, slices and pointers,
+	// before storing them, so that the observed records are not changed when the caller mutates the logged
+	// values afterward. Copying is best-effort and based on reflection, so it makes logging significantly
+	// slower and allocates for every logged value, that is why it is disabled by default.
+	// Errors, channels and functions are stored as is, unexported struct fields are copied shallowly.
+	CopyValues bool
+
+	// AddContext makes the handler store the context passed to Handle, see LoggedRecord.Context,
+	// e.g. to assert that the right context reached the log call.
+	AddContext bool
+
 	// MaxLogs is the maximum number of logs to store. If this is zero, the
 	// default, then the number of logs stored is unlimited.
 	// If ObservedLogs is set, then MaxLogs is ignored.
 	MaxLogs uint
 
+	// MaxBytes is the approximate maximum size in bytes of the logs to store, the oldest logs are evicted
+	// when it is exceeded, but the latest log is always kept. The size of the log is estimated once, when it is
+	// stored, from its message and attributes. If this is zero, the default, then the size is unlimited.
+	// MaxBytes composes with MaxLogs, whichever limit is hit first.
+	// If ObservedLogs is set, then MaxBytes is ignored.
+	MaxBytes uint
+
+	// OnEvict is called for every record that is dropped from the collection because of the MaxLogs
+	// or MaxBytes limit, or because the fixed size ObservedLogsRing overwrites it. It is called outside
+	// of the collection lock, so it is safe to use the collection from the callback.
+	// If ObservedLogs is set, then OnEvict is applied only to ObservedLogsDefault and ObservedLogsRing.
+	OnEvict func(LoggedRecord)
+
+	// SubscriberBuffer is the maximum number of records queued for each subscriber created with
+	// Subscriber.Subscribe that are not received from the channel yet. When it is exceeded, the oldest
+	// queued records are dropped, so that slow subscribers never block logging and see the latest records.
+	// If this is zero, the default, then the queue is unbounded and no records are dropped.
+	// If ObservedLogs is set, then SubscriberBuffer is applied only to the collections provided by this package.
+	SubscriberBuffer uint
+
+	// AddSeq makes the collection assign LoggedRecord.Seq to every added record, so that the records
+	// logged concurrently can be ordered deterministically even when their time is the same.
+	// Sequence numbers start from 1 and are never reused, even after TakeAll or Reset.
+	// If ObservedLogs is set, then AddSeq is applied only to the collections provided by this package.
+	AddSeq bool
+
+	// Dedup makes the collection collapse consecutive identical records, i.e. with the same level, message
+	// and attributes, into the first one incrementing its LoggedRecord.Repeated counter, so that repetitive
+	// logging, e.g. in a retry loop, does not push other records out of the fixed size collection.
+	// Collapsed records are not stored, so they are not delivered to subscribers and do not get Seq,
+	// but they are counted by Total.
+	// If ObservedLogs is set, then Dedup is applied only to ObservedLogsDefault and ObservedLogsRing.
+	Dedup bool
+
+	// Sample makes the handler store only a subset of the records, e.g. when the observer is used
+	// as an in-memory buffer of the recent logs in a busy service. Records are sampled before they are stored,
+	// so MaxLogs and MaxBytes limits apply to the kept records only. Sampled out records are counted
+	// as dropped by the collections provided by this package, see DroppedCounter, and are still forwarded to Next.
+	Sample *SampleOptions
+
+	// OnRecord is called for every observed record after it is stored, e.g. to update metrics or dump the logs
+	// when the specific record is observed. It runs synchronously on the logging goroutine, so it must be fast.
+	// It is called outside of the collection lock, so it is safe to use the collection from the callback,
+	// and a panic in the callback is propagated to the logging call, leaving the collection consistent.
+	OnRecord func(LoggedRecord)
+
 	// ObservedLogs collection implementation. If not set then ObservedLogsDefault is used.
-	// When set - MaxLogs is ignored.
+	// When set - MaxLogs and MaxBytes are ignored.
 	ObservedLogs ObservedLogs
 }
 
@@ -57,8 +258,11 @@ var _ slog.Handler = (*contextObserver)(nil)
 type contextObserver struct {
 	opts   HandlerOptions
 	logs   ObservedLogs
+	next   slog.Handler
+	test   *testingOptions
 	attrs  []slog.Attr
 	groups []slog.Attr
+	sample *sampler
 }
 
 // New creates new slog.Handler that buffers logs in memory.
@@ -70,17 +274,65 @@ func New(opts *HandlerOptions) (slog.Handler, ObservedLogs) {
 
 	ol := opts.ObservedLogs
 	if ol == nil {
-		ol = NewObservedLogsDefault(opts.MaxLogs)
+		dl := NewObservedLogsDefault(opts.MaxLogs)
+		dl.maxBytes = int(opts.MaxBytes)
+		ol = dl
+	}
+
+	if opts.OnEvict != nil {
+		switch l := ol.(type) {
+		case *ObservedLogsDefault:
+			l.setOnEvict(opts.OnEvict)
+		case *ObservedLogsRing:
+			l.setOnEvict(opts.OnEvict)
+		}
+	}
+
+	if opts.SubscriberBuffer > 0 {
+		switch l := ol.(type) {
+		case *ObservedLogsDefault:
+			l.subs.setBuffer(int(opts.SubscriberBuffer))
+		case *ObservedLogsRing:
+			l.subs.setBuffer(int(opts.SubscriberBuffer))
+		case *ObservedLogsChannel:
+			l.subs.setBuffer(int(opts.SubscriberBuffer))
+		}
+	}
+
+	if opts.Dedup {
+		switch l := ol.(type) {
+		case *ObservedLogsDefault:
+			l.setDedup(true)
+		case *ObservedLogsRing:
+			l.setDedup(true)
+		}
+	}
+
+	if opts.AddSeq {
+		switch l := ol.(type) {
+		case *ObservedLogsDefault:
+			l.setAddSeq(true)
+		case *ObservedLogsRing:
+			l.setAddSeq(true)
+		case *ObservedLogsChannel:
+			l.addSeq.Store(true)
+		}
 	}
 
 	return &contextObserver{
-		opts: *opts,
-		logs: ol,
+		opts:   *opts,
+		logs:   ol,
+		next:   opts.Next,
+		sample: newSampler(opts.Sample),
 	}, ol
 }
 
 // Enabled implements slog.Handler: reports whether the handler handles records at the given level.
-func (c contextObserver) Enabled(_ context.Context, level slog.Level) bool {
+func (c contextObserver) Enabled(ctx context.Context, level slog.Level) bool {
+	return c.enabled(level) || (c.next != nil && c.next.Enabled(ctx, level))
+}
+
+func (c contextObserver) enabled(level slog.Level) bool {
 	minLevel := slog.LevelInfo
 	if c.opts.Level != nil {
 		minLevel = c.opts.Level.Level()
@@ -90,8 +342,36 @@ func (c contextObserver) Enabled(_ context.Context, level slog.Level) bool {
 }
 
 // Handle implements slog.Handler: handles the Record.
-func (c contextObserver) Handle(_ context.Context, record slog.Record) error {
-	rc := slog.NewRecord(record.Time, record.Level, record.Message, 0)
+func (c contextObserver) Handle(ctx context.Context, record slog.Record) error {
+	if c.enabled(record.Level) {
+		c.handle(ctx, record)
+	}
+
+	if c.next != nil && c.next.Enabled(ctx, record.Level) {
+		return c.next.Handle(ctx, record)
+	}
+	return nil
+}
+
+func (c contextObserver) handle(ctx context.Context, record slog.Record) {
+	if !c.sample.keep(record.Message) {
+		if d, ok := c.logs.(sampledOutCounter); ok {
+			d.sampledOut()
+		}
+		return
+	}
+
+	var pc uintptr
+	if c.opts.AddSource {
+		pc = record.PC
+	}
+
+	recordTime := record.Time
+	if c.opts.Now != nil {
+		recordTime = c.opts.Now()
+	}
+
+	rc := slog.NewRecord(recordTime, record.Level, record.Message, pc)
 	attrs := c.attrs[:len(c.attrs):len(c.attrs)]
 
 	recordAttrs := make([]slog.Attr, 0, record.NumAttrs())
@@ -99,23 +379,67 @@ func (c contextObserver) Handle(_ context.Context, record slog.Record) error {
 		recordAttrs = append(recordAttrs, attr)
 		return true
 	})
+	for _, extract := range c.opts.ContextExtractors {
+		if extract != nil {
+			recordAttrs = append(recordAttrs, extract(ctx)...)
+		}
+	}
+	recordAttrs = c.replaceAttrs(c.groupNames(), c.copyAttrs(c.resolveAttrs(recordAttrs)))
 
 	if len(c.groups) > 0 {
-		if len(recordAttrs) > 0 {
-			currentGroupIdx := len(c.groups) - 1
-			c.groups[currentGroupIdx].Value = slog.GroupValue(append(c.groups[currentGroupIdx].Value.Group(), recordAttrs...)...)
+		// build nested groups from the innermost one, handler groups must not be modified
+		// as they are shared between all the records and derived handlers.
+		// Groups that end up without attributes are dropped the same way slog handlers do.
+		members := recordAttrs
+		for i := len(c.groups) - 1; i >= 0; i-- {
+			groupMembers := c.groups[i].Value.Group()
+			groupMembers = append(groupMembers[:len(groupMembers):len(groupMembers)], members...)
+			if !hasAttrs(groupMembers) {
+				members = nil
+				continue
+			}
+			members = []slog.Attr{{Key: c.groups[i].Key, Value: slog.GroupValue(groupMembers...)}}
 		}
+		attrs = append(attrs, members...)
+	} else {
+		attrs = append(attrs, recordAttrs...)
+	}
 
-		for i := len(c.groups) - 1; i >= 1; i-- {
-			c.groups[i-1].Value = slog.GroupValue(append(c.groups[i-1].Value.Group(), c.groups[i])...)
-		}
-		attrs = append(attrs, c.groups[0])
+	lr := LoggedRecord{Record: rc, Attrs: attrs}
+	if c.opts.AddContext {
+		lr.ctx = ctx
+		AddRecords(c.logs, []LoggedRecord{lr})
 	} else {
-		attrs = append(recordAttrs, attrs...)
+		c.logs.Add(rc, attrs)
 	}
 
-	c.logs.Add(rc, attrs)
-	return nil
+	if c.test != nil {
+		c.test.tb.Helper()
+		if c.test.tee {
+			c.test.tb.Logf("%s", lr)
+		}
+		c.failOn(lr)
+	}
+
+	if c.opts.OnRecord != nil {
+		c.opts.OnRecord(lr)
+	}
+}
+
+// failOn fails the test if the record satisfies the FailOn option function and is not allowed by its patterns.
+func (c contextObserver) failOn(r LoggedRecord) {
+	if c.test.failOn == nil || !c.test.failOn(r) {
+		return
+	}
+
+	for _, re := range c.test.failOnAllow {
+		if re.MatchString(r.Record.Message) {
+			return
+		}
+	}
+
+	c.test.tb.Helper()
+	c.test.tb.Errorf("Unexpected log observed: %s %q %v", r.Record.Level, r.Record.Message, r.AttrsMap())
 }
 
 // WithAttrs implements slog.Handler: returns a new Handler whose attributes consist of
@@ -124,15 +448,22 @@ func (c contextObserver) WithAttrs(attrs []slog.Attr) slog.Handler {
 	co := contextObserver{
 		opts:   c.opts,
 		logs:   c.logs,
-		groups: c.groups[:len(c.groups):len(c.groups)],
+		test:   c.test,
+		groups: slices.Clone(c.groups),
 		attrs:  c.attrs[:len(c.attrs):len(c.attrs)],
+		sample: c.sample,
+	}
+	if c.next != nil {
+		co.next = c.next.WithAttrs(attrs)
 	}
 
+	attrs = c.replaceAttrs(c.groupNames(), c.copyAttrs(c.resolveAttrs(attrs)))
 	if len(c.groups) == 0 {
 		co.attrs = append(co.attrs, attrs...)
 	} else {
 		currentGroupIdx := len(co.groups) - 1
-		co.groups[currentGroupIdx].Value = slog.GroupValue(append(co.groups[currentGroupIdx].Value.Group(), attrs...)...)
+		members := co.groups[currentGroupIdx].Value.Group()
+		co.groups[currentGroupIdx].Value = slog.GroupValue(append(members[:len(members):len(members)], attrs...)...)
 	}
 
 	return &co
@@ -144,9 +475,74 @@ func (c contextObserver) WithGroup(name string) slog.Handler {
 	co := contextObserver{
 		opts:   c.opts,
 		logs:   c.logs,
+		test:   c.test,
 		attrs:  c.attrs[:len(c.attrs):len(c.attrs)],
 		groups: append(c.groups[:len(c.groups):len(c.groups)], slog.Group(name)),
+		sample: c.sample,
+	}
+	if c.next != nil {
+		co.next = c.next.WithGroup(name)
 	}
 
 	return &co
 }
+
+func (c contextObserver) groupNames() []string {
+	if c.opts.ReplaceAttr == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(c.groups))
+	for _, g := range c.groups {
+		names = append(names, g.Key)
+	}
+	return names
+}
+
+// resolveAttrs resolves slog.LogValuer attribute values recursively, unless KeepLogValuers is set.
+func (c contextObserver) resolveAttrs(attrs []slog.Attr) []slog.Attr {
+	if c.opts.KeepLogValuers {
+		return attrs
+	}
+
+	res := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() == slog.KindGroup {
+			a.Value = slog.GroupValue(c.resolveAttrs(a.Value.Group())...)
+		}
+		res[i] = a
+	}
+	return res
+}
+
+// replaceAttrs applies ReplaceAttr option to the attributes recursively, dropping the empty ones.
+// Members of the groups returned by ReplaceAttr are replaced as well, the same way slog built-in handlers do.
+func (c contextObserver) replaceAttrs(groups []string, attrs []slog.Attr) []slog.Attr {
+	if c.opts.ReplaceAttr == nil {
+		return attrs
+	}
+
+	res := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() != slog.KindGroup {
+			a = c.opts.ReplaceAttr(groups, a)
+			if isEmptyAttr(a) {
+				continue
+			}
+			a.Value = a.Value.Resolve()
+			if a.Value.Kind() != slog.KindGroup {
+				res = append(res, a)
+				continue
+			}
+		}
+
+		groupGroups := groups
+		if a.Key != "" {
+			groupGroups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		res = append(res, slog.Attr{Key: a.Key, Value: slog.GroupValue(c.replaceAttrs(groupGroups, a.Value.Group())...)})
+	}
+	return res
+}
@@ -1,9 +1,17 @@
 package observer
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"strings"
+	"sync"
 	"testing"
+	"testing/slogtest"
 	"time"
 
 	"github.com/stretchr/testify/assert"
@@ -97,30 +105,30 @@ func testObserverWith(t *testing.T, ho *HandlerOptions) {
 		{
 			Record: record,
 			Attrs: []slog.Attr{
-				slog.Int("i", 0),
 				slog.Int("a", 1),
 				slog.Int("b", 2),
 				slog.Int("c", 3),
+				slog.Int("i", 0),
 			},
 		},
 		{
 			Record: record,
 			Attrs: []slog.Attr{
-				slog.Int("i", 1),
 				slog.Int("a", 1),
 				slog.Int("b", 2),
 				slog.Int("c", 3),
 				slog.Int("d", 4),
+				slog.Int("i", 1),
 			},
 		},
 		{
 			Record: record,
 			Attrs: []slog.Attr{
-				slog.Int("i", 2),
 				slog.Int("a", 1),
 				slog.Int("b", 2),
 				slog.Int("c", 3),
 				slog.Int("e", 5),
+				slog.Int("i", 2),
 			},
 		},
 	}, logs.AllUntimed(), "expected no field sharing between WithAttrs siblings")
@@ -177,6 +185,64 @@ func testObserverWithGroup(t *testing.T, ho *HandlerOptions) {
 			},
 		}, records[0].AttrsMap())
 	})
+
+	t.Run("reused WithGroup", func(t *testing.T) {
+		grouped := logger.WithGroup("foo").With(slog.Int("i", 2))
+		grouped.Info("foo", slog.Int("j", 3))
+		grouped.Info("bar", slog.Int("k", 4))
+		grouped.With(slog.Int("l", 5))
+		grouped.Info("baz")
+
+		assert.Equal(t, []map[string]any{
+			{"i": int64(1), "foo": map[string]any{"i": int64(2), "j": int64(3)}},
+			{"i": int64(1), "foo": map[string]any{"i": int64(2), "k": int64(4)}},
+			{"i": int64(1), "foo": map[string]any{"i": int64(2)}},
+		}, AttrsMaps(logs), "record and derived handler attrs must not leak to the other records")
+		Reset(logs)
+	})
+
+	t.Run("concurrent WithGroup", func(t *testing.T) {
+		const goroutines, perGoroutine = 8, 50
+
+		grouped := logger.WithGroup("foo").With(slog.Int("i", 2)).WithGroup("bar")
+
+		var wg sync.WaitGroup
+		for g := 0; g < goroutines; g++ {
+			g := g
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < perGoroutine; j++ {
+					grouped.Info("foo", slog.Int("g", g), slog.Int("j", j))
+				}
+			}()
+		}
+		wg.Wait()
+
+		records := logs.TakeAll()
+		require.Len(t, records, goroutines*perGoroutine)
+		for _, r := range records {
+			bar := r.AttrsMap()["foo"].(map[string]any)["bar"].(map[string]any)
+			assert.Len(t, bar, 2, "record attrs must not leak to the other records: %v", bar)
+		}
+	})
+
+	t.Run("WithGroup without attrs", func(t *testing.T) {
+		logger.WithGroup("foo").Info("foo")
+		logger.WithGroup("foo").WithGroup("bar").Info("bar")
+		logger.WithGroup("foo").With(slog.Int("i", 2)).WithGroup("bar").Info("baz")
+		logger.WithGroup("foo").Info("qux", slog.Attr{})
+
+		// checked with the slog.NewTextHandler() - should match "msg=foo i=1", "msg=bar i=1", "msg=baz i=1 foo.i=2"
+		// and "msg=qux i=1"
+		assert.Equal(t, []map[string]any{
+			{"i": int64(1)},
+			{"i": int64(1)},
+			{"i": int64(1), "foo": map[string]any{"i": int64(2)}},
+			{"i": int64(1)},
+		}, AttrsMaps(logs), "groups without attrs must be dropped")
+		Reset(logs)
+	})
 }
 
 func TestFilters(t *testing.T) {
@@ -323,6 +389,21 @@ func testFilters(t *testing.T, ho *HandlerOptions) {
 			filtered: logs.FilterLevelExact(slog.LevelWarn),
 			want:     records[9:10],
 		},
+		{
+			msg:      "filter levels",
+			filtered: FilterLevels(logs, slog.LevelWarn, slog.LevelError),
+			want:     records[9:11],
+		},
+		{
+			msg:      "filter levels with duplicates",
+			filtered: FilterLevels(logs, slog.LevelError, slog.LevelWarn, slog.LevelError),
+			want:     records[9:11],
+		},
+		{
+			msg:      "filter levels with no levels",
+			filtered: FilterLevels(logs),
+			want:     []LoggedRecord{},
+		},
 	}
 
 	for _, tt := range tests {
@@ -331,6 +412,32 @@ func testFilters(t *testing.T, ho *HandlerOptions) {
 	}
 }
 
+func TestFilterAttrsPresence(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testFilterAttrsPresence(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testFilterAttrsPresence(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testFilterAttrsPresence(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+}
+
+func testFilterAttrsPresence(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	logger.Info("bare")
+	logger.Info("empty attr only", slog.Attr{})
+	logger.Info("empty attrs only", slog.Attr{}, slog.Attr{})
+	logger.Info("with attr", slog.Int("i", 1))
+	logger.Info("with attr and empty attr", slog.Attr{}, slog.Int("i", 2))
+
+	assert.Equal(t, []string{"bare", "empty attr only", "empty attrs only"}, Messages(FilterEmptyAttrs(logs)))
+	assert.Equal(t, []string{"with attr", "with attr and empty attr"}, Messages(FilterHasAttrs(logs)))
+}
+
 func TestMaxLogs(t *testing.T) {
 	t.Run("ObservedLogs not set", func(t *testing.T) {
 		testMaxLogs(t, &HandlerOptions{MaxLogs: 3})
@@ -400,6 +507,37 @@ func BenchmarkMaxLogs(b *testing.B) {
 	})
 }
 
+func BenchmarkCount(b *testing.B) {
+	b.Run("ObservedLogsDefault", func(b *testing.B) {
+		// BenchmarkCount/ObservedLogsDefault         	  170707	      6452 ns/op	       0 B/op	       0 allocs/op
+		benchmarkCount(b, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	b.Run("ObservedLogsRing", func(b *testing.B) {
+		// BenchmarkCount/ObservedLogsRing            	  132015	      8947 ns/op	       0 B/op	       0 allocs/op
+		benchmarkCount(b, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	b.Run("ObservedLogsRing fixed", func(b *testing.B) {
+		// BenchmarkCount/ObservedLogsRing_fixed      	  528568	      2245 ns/op	       0 B/op	       0 allocs/op
+		benchmarkCount(b, &HandlerOptions{ObservedLogs: NewObservedLogsRing(50)})
+	})
+}
+
+func benchmarkCount(b *testing.B, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+	for i := 0; i < 100; i++ {
+		logger.Info("log", slog.Int("i", i))
+		logger.Warn("warn", slog.Int("i", i))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CountLevel(logs, slog.LevelWarn)
+		CountMessage(logs, "log")
+	}
+}
+
 func benchmarkMaxLogs(b *testing.B, ho *HandlerOptions) {
 	handler, _ := New(ho)
 	logger := slog.New(handler)
@@ -410,3 +548,2629 @@ func benchmarkMaxLogs(b *testing.B, ho *HandlerOptions) {
 		}
 	}
 }
+
+func TestWithDeltas(t *testing.T) {
+	all := []time.Duration{0, time.Hour, time.Second, 0, 2 * time.Second}
+
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testWithDeltas(t, nil, all)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testWithDeltas(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)}, all)
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testWithDeltas(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)}, all)
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		// ring buffer keeps only the latest three records, the oldest kept one has no previous record
+		testWithDeltas(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(3)}, []time.Duration{0, 0, 2 * time.Second})
+	})
+}
+
+func testWithDeltas(t *testing.T, ho *HandlerOptions, want []time.Duration) {
+	handler, logs := New(ho)
+	ctx := context.Background()
+
+	assert.Equal(t, []LoggedRecordDelta{}, WithDeltas(logs))
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, ts := range []time.Time{
+		start.Add(-time.Hour),
+		start,
+		start.Add(time.Second),
+		{}, // zero time record has zero delta and is skipped when looking for the previous record
+		start.Add(3 * time.Second),
+	} {
+		require.NoError(t, handler.Handle(ctx, slog.NewRecord(ts, slog.LevelInfo, fmt.Sprintf("log %d", i), 0)))
+	}
+
+	var (
+		msgs   []string
+		deltas []time.Duration
+	)
+	for _, r := range WithDeltas(logs) {
+		msgs = append(msgs, r.Record.Message)
+		deltas = append(deltas, r.Delta)
+	}
+
+	wantMsgs := []string{"log 0", "log 1", "log 2", "log 3", "log 4"}
+	assert.Equal(t, wantMsgs[len(wantMsgs)-len(want):], msgs)
+	assert.Equal(t, want, deltas)
+}
+
+func TestReset(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testReset(t, nil)
+	})
+	t.Run("ObservedLogs not set fixed", func(t *testing.T) {
+		testReset(t, &HandlerOptions{MaxLogs: 3})
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testReset(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testReset(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testReset(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(3)})
+	})
+	t.Run("not Truncater", func(t *testing.T) {
+		testReset(t, &HandlerOptions{ObservedLogs: plainLogs{NewObservedLogsDefault(0)}})
+	})
+}
+
+func testReset(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	for i := 0; i < 5; i++ {
+		logger.Info("before reset", slog.Int("i", i))
+	}
+	require.NotZero(t, logs.Len())
+
+	Reset(logs)
+	assertEmpty(t, logs)
+	assertReleased(t, logs)
+
+	for i := 0; i < 2; i++ {
+		logger.Info("after reset", slog.Int("i", i))
+	}
+
+	record := slog.Record{Level: slog.LevelInfo, Message: "after reset"}
+	assert.Equal(t, []LoggedRecord{
+		{Record: record, Attrs: []slog.Attr{slog.Int("i", 0)}},
+		{Record: record, Attrs: []slog.Attr{slog.Int("i", 1)}},
+	}, logs.AllUntimed())
+}
+
+// assertReleased checks that the storage of the empty collection does not keep references to the removed records,
+// so they can be garbage collected, and that fixed size collections keep their capacity.
+func assertReleased(t *testing.T, logs ObservedLogs) {
+	t.Helper()
+
+	var (
+		storage []LoggedRecord
+		fixed   bool
+	)
+	switch ol := logs.(type) {
+	case *ObservedLogsDefault:
+		storage, fixed = ol.logs, ol.fixed
+	case *ObservedLogsRing:
+		storage, fixed = ol.logs, ol.fixed
+	}
+
+	for i, r := range storage[:cap(storage)] {
+		assert.Zero(t, r, "slot %d is not released", i)
+	}
+	if fixed {
+		assert.Equal(t, 3, cap(storage), "fixed size collection lost its capacity")
+	}
+}
+
+func TestTakeAllFixed(t *testing.T) {
+	handler, logs := New(&HandlerOptions{MaxLogs: 3})
+	logger := slog.New(handler)
+
+	for i := 0; i < 5; i++ {
+		logger.Info("before take", slog.Int("i", i))
+	}
+	require.Len(t, logs.TakeAll(), 3)
+	assertEmpty(t, logs)
+	assertReleased(t, logs)
+
+	for i := 0; i < 5; i++ {
+		logger.Info("after take", slog.Int("i", i))
+	}
+	assert.Equal(t, []map[string]any{{"i": int64(2)}, {"i": int64(3)}, {"i": int64(4)}}, AttrsMaps(logs))
+}
+
+func TestAllSince(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testAllSince(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testAllSince(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testAllSince(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+}
+
+func testAllSince(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	logger.Info("log", slog.Int("i", 0))
+	cursor := logs.(CursorReader).Cursor()
+
+	records, cursor, dropped := logs.(CursorReader).AllSince(cursor)
+	assert.Empty(t, records)
+	assert.Zero(t, dropped)
+
+	logger.Info("log", slog.Int("i", 1))
+	logger.Info("log", slog.Int("i", 2))
+
+	records, cursor, dropped = logs.(CursorReader).AllSince(cursor)
+	require.Len(t, records, 2)
+	assert.Zero(t, dropped)
+	assert.Equal(t, map[string]any{"i": int64(1)}, records[0].AttrsMap())
+	assert.Equal(t, map[string]any{"i": int64(2)}, records[1].AttrsMap())
+	assert.Equal(t, 3, logs.Len(), "AllSince must not mutate the collection")
+
+	records, _, dropped = logs.(CursorReader).AllSince(Cursor{})
+	assert.Len(t, records, 3)
+	assert.Zero(t, dropped)
+
+	logger.Info("log", slog.Int("i", 3))
+	Reset(logs)
+	logger.Info("log", slog.Int("i", 4))
+
+	records, _, dropped = logs.(CursorReader).AllSince(cursor)
+	require.Len(t, records, 1)
+	assert.Equal(t, 1, dropped, "truncated records are reported as dropped")
+	assert.Equal(t, map[string]any{"i": int64(4)}, records[0].AttrsMap())
+}
+
+func TestAllSinceDropped(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testAllSinceDropped(t, &HandlerOptions{MaxLogs: 3})
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testAllSinceDropped(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(3)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testAllSinceDropped(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(3)})
+	})
+}
+
+func testAllSinceDropped(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	logger.Info("log", slog.Int("i", 0))
+	cursor := logs.(CursorReader).Cursor()
+
+	// wrap the buffer more than once
+	for i := 1; i <= 7; i++ {
+		logger.Info("log", slog.Int("i", i))
+	}
+
+	records, cursor, dropped := logs.(CursorReader).AllSince(cursor)
+	require.Len(t, records, 3)
+	assert.Equal(t, 4, dropped)
+	assert.Equal(t, map[string]any{"i": int64(5)}, records[0].AttrsMap())
+	assert.Equal(t, map[string]any{"i": int64(7)}, records[2].AttrsMap())
+
+	logger.Info("log", slog.Int("i", 8))
+
+	records, _, dropped = logs.(CursorReader).AllSince(cursor)
+	require.Len(t, records, 1)
+	assert.Zero(t, dropped)
+	assert.Equal(t, map[string]any{"i": int64(8)}, records[0].AttrsMap())
+}
+
+func TestAllSinceConcurrent(t *testing.T) {
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testAllSinceConcurrent(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsDefault fixed", func(t *testing.T) {
+		testAllSinceConcurrent(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(10)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testAllSinceConcurrent(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testAllSinceConcurrent(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(10)})
+	})
+}
+
+func testAllSinceConcurrent(t *testing.T, ho *HandlerOptions) {
+	const total = 1000
+
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < total; i++ {
+			logger.Info("log", slog.Int("i", i))
+		}
+	}()
+
+	var (
+		cursor   Cursor
+		received int
+		dropped  int
+		last     = int64(-1)
+	)
+	read := func() {
+		var (
+			records []LoggedRecord
+			d       int
+		)
+		records, cursor, d = logs.(CursorReader).AllSince(cursor)
+		dropped += d
+		for _, r := range records {
+			i := r.AttrsMap()["i"].(int64)
+			require.Greater(t, i, last, "records must be returned in order and only once")
+			last = i
+		}
+		received += len(records)
+	}
+
+	for {
+		select {
+		case <-done:
+			read()
+			assert.Equal(t, total, received+dropped)
+			assert.Equal(t, int64(total-1), last)
+			return
+		default:
+			read()
+		}
+	}
+}
+
+func TestFilterGroup(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testFilterGroup(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testFilterGroup(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testFilterGroup(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+}
+
+func testFilterGroup(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+	ctx := context.Background()
+
+	// slog.Logger drops empty groups, so add the record with the empty group directly
+	emptyGroup := slog.NewRecord(time.Now(), slog.LevelInfo, "empty group", 0)
+	logs.Add(emptyGroup, []slog.Attr{{Key: "request", Value: slog.GroupValue()}})
+
+	logger.Info("top-level group", slog.Group("request", slog.String("id", "1")))
+	logger.WithGroup("request").Info("WithGroup", slog.String("id", "2"))
+	logger.Info("non-group value", slog.String("request", "3"))
+	logger.Info("nested group", slog.Group("http", slog.Group("request", slog.String("id", "4"))))
+	logger.WithGroup("http").WithGroup("request").Info("nested WithGroup", slog.String("id", "5"))
+	logger.LogAttrs(ctx, slog.LevelInfo, "nested non-group value", slog.Group("http", slog.String("request", "6")))
+
+	assert.Equal(t, []string{"empty group", "top-level group", "WithGroup"}, Messages(FilterGroup(logs, "request")))
+	assert.Equal(t, []string{
+		"empty group", "top-level group", "WithGroup", "nested group", "nested WithGroup",
+	}, Messages(FilterGroupDeep(logs, "request")))
+	assert.Equal(t, []string{"nested group", "nested WithGroup", "nested non-group value"}, Messages(FilterGroup(logs, "http")))
+	assert.Empty(t, Messages(FilterGroupDeep(logs, "id")))
+}
+
+func TestFilterAttrInGroup(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testFilterAttrInGroup(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testFilterAttrInGroup(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testFilterAttrInGroup(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+}
+
+func testFilterAttrInGroup(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	logger.Info("http error", slog.Group("http", slog.Int("status", 500)))
+	logger.Info("http ok", slog.Group("http", slog.Int("status", 200)))
+	logger.Info("top-level status", slog.Int("status", 500))
+	logger.Info("other group", slog.Group("grpc", slog.Int("status", 500)))
+	logger.WithGroup("http").Info("WithGroup", slog.Int("status", 500))
+	logger.Info("nested", slog.Group("upstream", slog.Group("http", slog.Int("status", 500))))
+	logger.Info("inlined", slog.Group("", slog.Group("http", slog.Int("status", 500))))
+
+	status := slog.Int("status", 500)
+	assert.Equal(t, []string{"http error", "WithGroup", "inlined"}, Messages(FilterAttrInGroup(logs, []string{"http"}, status)))
+	assert.Equal(t, []string{"nested"}, Messages(FilterAttrInGroup(logs, []string{"upstream", "http"}, status)))
+	assert.Empty(t, Messages(FilterAttrInGroup(logs, []string{"upstream", "grpc"}, status)))
+	assert.Equal(t, Messages(logs.FilterAttr(status)), Messages(FilterAttrInGroup(logs, nil, status)))
+}
+
+func TestFilterAttrValue(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testFilterAttrValue(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testFilterAttrValue(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testFilterAttrValue(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+}
+
+func testFilterAttrValue(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	logger.Info("ok", slog.Int("status", 200))
+	logger.Info("not found", slog.Int("status", 404))
+	logger.Info("internal", slog.Int("status", 500))
+	logger.Info("grouped", slog.Group("response", slog.Int("status", 503)))
+	logger.WithGroup("response").Info("WithGroup", slog.Int("status", 502))
+	logger.Info("string status", slog.String("status", "600"))
+	logger.Info("path", slog.String("path", "/api/v1/users"))
+	logger.Info("map", slog.Any("query", map[string]string{"a": "b"}))
+
+	serverErrors := FilterAttrValue(logs, "status", func(v slog.Value) bool {
+		return v.Kind() == slog.KindInt64 && v.Int64() >= 500
+	})
+	assert.Equal(t, []string{"internal", "grouped", "WithGroup"}, Messages(serverErrors))
+
+	api := FilterAttrValue(logs, "path", func(v slog.Value) bool {
+		return strings.HasPrefix(v.String(), "/api/")
+	})
+	assert.Equal(t, []string{"path"}, Messages(api))
+
+	assert.Equal(t, []string{"path"}, Messages(FilterAttrValueSnippet(logs, "path", "/v1/")))
+	assert.Equal(t, []string{"internal", "grouped", "WithGroup"}, Messages(FilterAttrValueSnippet(logs, "status", "50")))
+	assert.Equal(t, []string{"string status"}, Messages(FilterAttrValueSnippet(logs, "status", "600")))
+	assert.Equal(t, []string{"map"}, Messages(FilterAttrValueSnippet(logs, "query", "a:b")))
+	assert.Empty(t, Messages(FilterAttrValueSnippet(logs, "path", "/v2/")))
+
+	groups := FilterAttrValue(logs, "response", func(slog.Value) bool {
+		return true
+	})
+	assert.Empty(t, Messages(groups), "group values must not be passed to the function")
+}
+
+func TestDistinct(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testDistinct(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testDistinct(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testDistinct(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+}
+
+func testDistinct(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	ctx := context.Background()
+
+	start := time.Now()
+	records := []LoggedRecord{
+		{
+			Record: slog.Record{Level: slog.LevelInfo, Message: "retry"},
+			Attrs:  []slog.Attr{slog.Int("attempt", 1)},
+		},
+		{
+			Record: slog.Record{Level: slog.LevelInfo, Message: "retry"},
+			Attrs:  []slog.Attr{slog.Int("attempt", 1)},
+		},
+		{
+			Record: slog.Record{Level: slog.LevelWarn, Message: "retry"},
+			Attrs:  []slog.Attr{slog.Int("attempt", 1)},
+		},
+		{
+			Record: slog.Record{Level: slog.LevelInfo, Message: "map"},
+			Attrs:  []slog.Attr{slog.Any("m", map[string]string{"a": "b"})},
+		},
+		{
+			Record: slog.Record{Level: slog.LevelInfo, Message: "retry"},
+			Attrs:  []slog.Attr{slog.Int("attempt", 2)},
+		},
+		{
+			Record: slog.Record{Level: slog.LevelInfo, Message: "map"},
+			Attrs:  []slog.Attr{slog.Any("m", map[string]string{"a": "b"})},
+		},
+		{
+			Record: slog.Record{Level: slog.LevelInfo, Message: "map"},
+			Attrs:  []slog.Attr{slog.Any("m", map[string]string{"a": "c"})},
+		},
+		{
+			Record: slog.Record{Level: slog.LevelInfo, Message: "slice"},
+			Attrs:  []slog.Attr{slog.Any("s", []string{"a"})},
+		},
+		{
+			Record: slog.Record{Level: slog.LevelInfo, Message: "slice"},
+			Attrs:  []slog.Attr{slog.Any("s", []string{"a"})},
+		},
+	}
+
+	for i, r := range records {
+		// every record has its own time, so that time is proven to be ignored
+		rec := slog.NewRecord(start.Add(time.Duration(i)*time.Second), r.Record.Level, r.Record.Message, 0)
+		rec.AddAttrs(r.Attrs...)
+		require.NoError(t, handler.Handle(ctx, rec))
+	}
+
+	assert.Equal(t, []LoggedRecord{
+		records[0],
+		records[2],
+		records[3],
+		records[4],
+		records[6],
+		records[7],
+	}, Distinct(logs).AllUntimed())
+	assert.Equal(t, len(records), logs.Len(), "Distinct must not mutate the collection")
+}
+
+func TestFilterIndexed(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testFilterIndexed(t, nil)
+	})
+	t.Run("ObservedLogs not set fixed", func(t *testing.T) {
+		testFilterIndexed(t, &HandlerOptions{MaxLogs: 4})
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testFilterIndexed(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testFilterIndexed(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testFilterIndexed(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(4)})
+	})
+	t.Run("ObservedLogsRing fixed not wrapped", func(t *testing.T) {
+		testFilterIndexed(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(100)})
+	})
+}
+
+func testFilterIndexed(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	// fixed size collections of 4 wrap and keep only the last 4 messages
+	for _, msg := range []string{"noise 1", "noise 2", "noise 3", "starting", "processing", "processing", "done"} {
+		logger.Info(msg)
+	}
+	n := logs.Len()
+
+	first := FilterIndexed(logs, func(i int, _ LoggedRecord) bool {
+		return i == 0
+	})
+	last := FilterIndexed(logs, func(i int, _ LoggedRecord) bool {
+		return i == n-1
+	})
+	assert.Equal(t, []string{"done"}, Messages(last))
+
+	var indices []int
+	FilterIndexed(logs, func(i int, _ LoggedRecord) bool {
+		indices = append(indices, i)
+		return false
+	})
+	for i := range indices {
+		assert.Equal(t, i, indices[i], "indices must be logical and sequential")
+	}
+
+	if n == 4 {
+		assert.Equal(t, []string{"starting"}, Messages(first))
+	} else {
+		assert.Equal(t, []string{"noise 1"}, Messages(first))
+	}
+}
+
+func TestFilterWithoutFieldKey(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testFilterWithoutFieldKey(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testFilterWithoutFieldKey(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testFilterWithoutFieldKey(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+}
+
+func testFilterWithoutFieldKey(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	logger.Info("clean", slog.String("user", "gopher"))
+	logger.Info("top-level", slog.String("password", "secret"))
+	logger.Info("slog.Group", slog.Group("auth", slog.Group("basic", slog.String("password", "secret"))))
+	logger.WithGroup("auth").WithGroup("basic").Info("WithGroup", slog.String("password", "secret"))
+	logger.With(slog.String("password", "secret")).WithGroup("auth").Info("With before WithGroup")
+	logger.WithGroup("auth").With(slog.String("password", "secret")).Info("With after WithGroup")
+	logger.Info("value contains key", slog.String("note", "password"))
+
+	clean := FilterWithoutFieldKey(logs, "password")
+	assert.Equal(t, []string{"clean", "value contains key"}, Messages(clean))
+	assert.Equal(t, []string{"clean", "value contains key"}, Messages(FilterWithoutFieldKey(FilterWithoutFieldKey(logs, "basic"), "password")))
+	assert.Equal(t, logs.Len(), FilterWithoutFieldKey(logs, "token").Len())
+}
+
+func TestFilterAttrKeyPrefix(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testFilterAttrKeyPrefix(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testFilterAttrKeyPrefix(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testFilterAttrKeyPrefix(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+}
+
+func testFilterAttrKeyPrefix(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	logger.Info("flat", slog.String("db.query", "select 1"))
+	logger.Info("slog.Group", slog.Group("db", slog.Int("rows", 1)))
+	logger.WithGroup("db").Info("WithGroup", slog.Int("rows", 1))
+	logger.Info("nested", slog.Group("storage", slog.String("db.name", "main")))
+	logger.Info("inlined group", slog.Group("", slog.String("db.name", "main")))
+	logger.Info("http", slog.Int("http.status", 200), slog.String("dbname", "main"))
+	logger.Info("empty group", slog.Group("db"))
+
+	assert.Equal(t, []string{"flat", "slog.Group", "WithGroup", "inlined group"}, Messages(FilterAttrKeyPrefix(logs, "db.")))
+	assert.Equal(t, []string{"nested"}, Messages(FilterAttrKeyPrefix(logs, "storage.db.")))
+	assert.Equal(t, []string{"http"}, Messages(FilterAttrKeyPrefix(logs, "http.")))
+	assert.Equal(t, FilterHasAttrs(logs).Len(), FilterAttrKeyPrefix(logs, "").Len())
+}
+
+func TestReplaceAttr(t *testing.T) {
+	var calls [][]string
+	replace := func(groups []string, a slog.Attr) slog.Attr {
+		calls = append(calls, append([]string{a.Key}, groups...))
+		switch a.Key {
+		case "password":
+			return slog.String("password", "***")
+		case "drop":
+			return slog.Attr{}
+		case "old":
+			return slog.Attr{Key: "new", Value: a.Value}
+		}
+		return a
+	}
+
+	handler, logs := New(&HandlerOptions{ReplaceAttr: replace})
+	logger := slog.New(handler)
+
+	logger.With(slog.String("password", "secret"), slog.Int("drop", 1)).
+		WithGroup("req").
+		With(slog.Int("old", 2)).
+		Info("msg",
+			slog.String("password", "secret"),
+			slog.Group("nested", slog.Int("old", 3), slog.Int("drop", 4)),
+		)
+
+	records := logs.TakeAll()
+	require.Len(t, records, 1)
+
+	assert.Equal(t, map[string]any{
+		"password": "***",
+		"req": map[string]any{
+			"new":      int64(2),
+			"password": "***",
+			"nested": map[string]any{
+				"new": int64(3),
+			},
+		},
+	}, records[0].AttrsMap())
+
+	assert.ElementsMatch(t, [][]string{
+		{"password"},
+		{"drop"},
+		{"old", "req"},
+		{"password", "req"},
+		{"old", "req", "nested"},
+		{"drop", "req", "nested"},
+	}, calls)
+}
+
+func TestReplaceAttrJSONHandlerFidelity(t *testing.T) {
+	replace := func(groups []string, a slog.Attr) slog.Attr {
+		// built-in attributes are not passed to the observer ReplaceAttr, keep them for the JSONHandler
+		if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == slog.MessageKey) {
+			return a
+		}
+
+		switch {
+		case a.Key == "drop":
+			return slog.Attr{}
+		case a.Key == "id":
+			return slog.String("id", "<id>")
+		case a.Key == "old":
+			return slog.Attr{Key: "new", Value: a.Value}
+		case a.Key == "expand":
+			return slog.Group("expanded", slog.Any("value", a.Value), slog.String("path", strings.Join(groups, "/")))
+		case len(groups) > 1:
+			return slog.String(a.Key, strings.Join(groups, "/"))
+		}
+		return a
+	}
+
+	var buf bytes.Buffer
+	handler, logs := New(&HandlerOptions{ReplaceAttr: replace, Next: slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: replace})})
+	logger := slog.New(handler)
+
+	logger.With("id", 1, "drop", 2).WithGroup("g1").With("old", 3).WithGroup("g2").Info("chain", "deep", 4, "drop", 5)
+	logger.Info("groups",
+		slog.Group("g1", "id", 1, slog.Group("g2", "deep", 2, "old", 3), slog.Group("", "inline", 4)),
+		slog.Group("", "id", 5),
+		slog.Group("g3", "drop", 6),
+	)
+	logger.WithGroup("g1").Info("expand", "expand", 1, slog.Any("lv", stringValuer("resolved")))
+
+	records := logs.All()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, len(records))
+
+	for i, r := range records {
+		var want map[string]any
+		require.NoError(t, json.Unmarshal([]byte(lines[i]), &want))
+		delete(want, slog.TimeKey)
+		delete(want, slog.LevelKey)
+		delete(want, slog.MessageKey)
+
+		gotJSON, err := json.Marshal(r.AttrsMap())
+		require.NoError(t, err)
+		var got map[string]any
+		require.NoError(t, json.Unmarshal(gotJSON, &got))
+
+		assert.Equal(t, want, got, "record %q, JSONHandler output: %s", r.Record.Message, lines[i])
+	}
+}
+
+func TestNow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	clock := func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	handler, logs := New(&HandlerOptions{Now: clock})
+	logger := slog.New(handler)
+
+	logger.Info("first")
+	logger.Info("second")
+
+	records := logs.All()
+	require.Len(t, records, 2)
+	assert.Equal(t, start.Add(time.Second), records[0].Record.Time)
+	assert.Equal(t, start.Add(2*time.Second), records[1].Record.Time)
+}
+
+func TestLevelVar(t *testing.T) {
+	var level slog.LevelVar
+	level.Set(slog.LevelWarn)
+
+	handler, logs := New(&HandlerOptions{Level: &level})
+	logger := slog.New(handler)
+	// derived handlers are created before the level change on purpose, they must see it as well
+	derived := logger.With(slog.String("component", "db")).WithGroup("g")
+
+	logger.Info("info before")
+	derived.Info("derived info before")
+	logger.Warn("warn before")
+	assert.False(t, handler.Enabled(context.Background(), slog.LevelInfo))
+
+	level.Set(slog.LevelDebug)
+	logger.Debug("debug after")
+	derived.Debug("derived debug after")
+	assert.True(t, handler.Enabled(context.Background(), slog.LevelDebug))
+
+	level.Set(slog.LevelError)
+	logger.Warn("warn after")
+	derived.Error("derived error after")
+
+	assert.Equal(t, []string{"warn before", "debug after", "derived debug after", "derived error after"}, Messages(logs))
+}
+
+func TestMessages(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testMessages(t, nil)
+	})
+	t.Run("ObservedLogs not set fixed", func(t *testing.T) {
+		testMessages(t, &HandlerOptions{MaxLogs: 3})
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testMessages(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testMessages(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testMessages(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(3)})
+	})
+}
+
+func testMessages(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	assert.Equal(t, []string{}, Messages(logs))
+
+	for i := 0; i < 5; i++ {
+		logger.Info(fmt.Sprintf("log %d", i))
+	}
+
+	// fixed size collections of 3 wrap and keep only the last 3 messages
+	want := []string{"log 0", "log 1", "log 2", "log 3", "log 4"}
+	want = want[len(want)-logs.Len():]
+
+	assert.Equal(t, want, Messages(logs))
+}
+
+func TestNext(t *testing.T) {
+	var buf bytes.Buffer
+	next := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+
+	handler, logs := New(&HandlerOptions{
+		Next: next,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == "password" {
+				return slog.String("password", "***")
+			}
+			return a
+		},
+	})
+	logger := slog.New(handler).With(slog.Int("i", 1)).WithGroup("g").With(slog.String("password", "secret"))
+
+	assert.True(t, handler.Enabled(context.Background(), slog.LevelDebug), "Enabled must take Next into account")
+
+	logger.Info("info", slog.Int("j", 2))
+	logger.Debug("debug")
+
+	assert.Equal(t, "level=INFO msg=info i=1 g.password=secret g.j=2\nlevel=DEBUG msg=debug i=1 g.password=secret\n", buf.String(),
+		"Next must receive original records and attrs")
+
+	records := logs.TakeAll()
+	require.Len(t, records, 1, "observer level must still be respected")
+	assert.Equal(t, map[string]any{
+		"i": int64(1),
+		"g": map[string]any{"password": "***", "j": int64(2)},
+	}, records[0].AttrsMap())
+}
+
+func TestAttrsOrder(t *testing.T) {
+	var buf bytes.Buffer
+	handler, logs := New(&HandlerOptions{Next: slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})})
+	logger := slog.New(handler).With(slog.Int("a", 1))
+
+	logger.Info("plain", slog.Int("b", 2))
+	logger.With(slog.Int("c", 3)).WithGroup("g").With(slog.Int("d", 4)).Info("grouped", slog.Int("e", 5))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "level=INFO msg=plain a=1 b=2", lines[0])
+	assert.Equal(t, "level=INFO msg=grouped a=1 c=3 g.d=4 g.e=5", lines[1])
+
+	records := logs.All()
+	require.Len(t, records, 2)
+	assert.Equal(t, lines, []string{records[0].String(), records[1].String()},
+		"observed attrs must be in the same order as slog built-in handlers output them")
+}
+
+// errorHandler is slog.Handler that fails to handle any record.
+type errorHandler struct {
+	slog.Handler
+	err error
+}
+
+func (h errorHandler) Handle(context.Context, slog.Record) error {
+	return h.err
+}
+
+func TestNextJSON(t *testing.T) {
+	t.Run("source and attrs", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler, logs := New(&HandlerOptions{Next: slog.NewJSONHandler(&buf, &slog.HandlerOptions{AddSource: true})})
+		logFromHelper(slog.New(handler).With(slog.Int("i", 1)).WithGroup("g"))
+
+		var got map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		assert.Equal(t, "from helper", got[slog.MessageKey])
+		assert.Equal(t, float64(1), got["i"])
+
+		src, ok := got[slog.SourceKey].(map[string]any)
+		require.True(t, ok, "Next must receive the original record PC")
+		assert.True(t, strings.HasSuffix(src["function"].(string), "observer.logFromHelper"), src["function"])
+
+		records := logs.TakeAll()
+		require.Len(t, records, 1)
+		assert.Equal(t, "from helper", records[0].Record.Message)
+		assert.Equal(t, map[string]any{"i": int64(1)}, records[0].AttrsMap())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		errNext := errors.New("next failed")
+		handler, logs := New(&HandlerOptions{Next: errorHandler{Handler: slog.NewJSONHandler(io.Discard, nil), err: errNext}})
+
+		err := handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0))
+		assert.ErrorIs(t, err, errNext)
+		assert.Equal(t, 1, logs.Len(), "record must be observed even if Next fails")
+	})
+}
+
+type userValuer struct {
+	id   int
+	name string
+}
+
+func (u userValuer) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("id", u.id), slog.Any("name", stringValuer(u.name)))
+}
+
+type panicValuer struct{}
+
+func (panicValuer) LogValue() slog.Value {
+	panic("boom")
+}
+
+func TestResolveLogValuers(t *testing.T) {
+	t.Run("resolved", func(t *testing.T) {
+		handler, logs := New(nil)
+		logger := slog.New(handler).With(slog.Any("owner", userValuer{id: 1, name: "root"}))
+
+		logger.Info("msg", slog.Any("user", userValuer{id: 42, name: "gopher"}), slog.Group("g", slog.Any("s", stringValuer("v"))))
+
+		records := logs.All()
+		require.Len(t, records, 1)
+		assert.Equal(t, []slog.Attr{
+			slog.Group("owner", slog.Int("id", 1), slog.String("name", "root")),
+			slog.Group("user", slog.Int("id", 42), slog.String("name", "gopher")),
+			slog.Group("g", slog.String("s", "v")),
+		}, records[0].Attrs)
+		assert.Equal(t, 1, logs.FilterAttr(slog.String("name", "gopher")).Len(), "FilterAttr must match resolved values")
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		handler, logs := New(nil)
+		slog.New(handler).Info("msg", slog.Any("p", panicValuer{}))
+
+		records := logs.All()
+		require.Len(t, records, 1)
+		err, ok := records[0].Attrs[0].Value.Any().(error)
+		require.True(t, ok, "panic is expected to be recovered by slog.Value.Resolve")
+		assert.Contains(t, err.Error(), "LogValue panicked")
+	})
+
+	t.Run("KeepLogValuers", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{KeepLogValuers: true})
+		user := userValuer{id: 42, name: "gopher"}
+		slog.New(handler).Info("msg", slog.Any("user", user))
+
+		records := logs.All()
+		require.Len(t, records, 1)
+		assert.Equal(t, []slog.Attr{slog.Any("user", user)}, records[0].Attrs)
+		assert.Equal(t, map[string]any{"user": map[string]any{"id": int64(42), "name": "gopher"}}, records[0].AttrsMap(),
+			"AttrsMap resolves values anyway")
+	})
+}
+
+func TestContextExtractors(t *testing.T) {
+	type ctxKey string
+
+	handler, logs := New(&HandlerOptions{
+		ContextExtractors: []func(context.Context) []slog.Attr{
+			func(ctx context.Context) []slog.Attr {
+				if id, ok := ctx.Value(ctxKey("request_id")).(string); ok {
+					return []slog.Attr{slog.String("request_id", id)}
+				}
+				return nil
+			},
+			func(ctx context.Context) []slog.Attr {
+				if id, ok := ctx.Value(ctxKey("trace_id")).(string); ok {
+					return []slog.Attr{slog.String("trace_id", id)}
+				}
+				return nil
+			},
+		},
+	})
+	logger := slog.New(handler)
+
+	ctx := context.WithValue(context.Background(), ctxKey("request_id"), "req-1")
+	logger.InfoContext(ctx, "request", slog.Int("i", 1))
+
+	ctx = context.WithValue(ctx, ctxKey("trace_id"), "trace-1")
+	logger.WithGroup("g").InfoContext(ctx, "traced")
+
+	logger.Info("no context")
+
+	assert.Equal(t, []map[string]any{
+		{"i": int64(1), "request_id": "req-1"},
+		{"g": map[string]any{"request_id": "req-1", "trace_id": "trace-1"}},
+		{},
+	}, AttrsMaps(logs))
+}
+
+func TestContextExtractorsNil(t *testing.T) {
+	handler, logs := New(&HandlerOptions{ContextExtractors: []func(context.Context) []slog.Attr{nil}})
+	logger := slog.New(handler)
+
+	require.NotPanics(t, func() {
+		logger.InfoContext(context.Background(), "msg", slog.Int("i", 1))
+	})
+	assert.Equal(t, []map[string]any{{"i": int64(1)}}, AttrsMaps(logs))
+}
+
+func TestAttrsMaps(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testAttrsMaps(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testAttrsMaps(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testAttrsMaps(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testAttrsMaps(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(3)})
+	})
+}
+
+func testAttrsMaps(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	assert.Equal(t, []map[string]any{}, AttrsMaps(logs))
+
+	logger.Info("bare")
+	logger.Info("flat", slog.Int("i", 1))
+	logger.Info("grouped", slog.Group("g", slog.Int("i", 2), slog.Group("nested", slog.String("s", "v"))))
+	logger.WithGroup("g").Info("WithGroup", slog.Int("i", 3))
+
+	assert.Equal(t, []map[string]any{
+		{"i": int64(1)},
+		{"g": map[string]any{"i": int64(2), "nested": map[string]any{"s": "v"}}},
+		{"g": map[string]any{"i": int64(3)}},
+	}, AttrsMaps(FilterHasAttrs(logs)))
+}
+
+func TestCountByLevel(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testCountByLevel(t, nil)
+	})
+	t.Run("ObservedLogs not set fixed", func(t *testing.T) {
+		testCountByLevel(t, &HandlerOptions{MaxLogs: 4})
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testCountByLevel(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testCountByLevel(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testCountByLevel(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(4)})
+	})
+}
+
+func testCountByLevel(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	assert.Equal(t, map[slog.Level]int{}, CountByLevel(logs))
+
+	// fixed size collections of 4 wrap and keep only the last 4 records
+	logger.Info("info")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Error("error")
+	logger.Info("info")
+	logger.Error("error")
+
+	counts := CountByLevel(logs)
+	if logs.Len() == 4 {
+		assert.Equal(t, map[slog.Level]int{slog.LevelInfo: 1, slog.LevelWarn: 1, slog.LevelError: 2}, counts)
+	} else {
+		assert.Equal(t, map[slog.Level]int{slog.LevelInfo: 3, slog.LevelWarn: 1, slog.LevelError: 2}, counts)
+	}
+	assert.Zero(t, counts[slog.LevelDebug])
+
+	assert.Equal(t, counts[slog.LevelInfo], CountLevel(logs, slog.LevelInfo))
+	assert.Equal(t, 2, CountLevel(logs, slog.LevelError))
+	assert.Zero(t, CountLevel(logs, slog.LevelDebug))
+
+	assert.Equal(t, counts[slog.LevelInfo], CountMessage(logs, "info"))
+	assert.Equal(t, 1, CountMessage(logs, "warn"))
+	assert.Zero(t, CountMessage(logs, "nope"))
+
+	allocs := testing.AllocsPerRun(10, func() {
+		CountLevel(logs, slog.LevelError)
+		CountMessage(logs, "error")
+	})
+	assert.Zero(t, allocs, "counting must not allocate")
+}
+
+func TestTakeN(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testTakeN(t, nil)
+	})
+	t.Run("ObservedLogs not set fixed", func(t *testing.T) {
+		testTakeN(t, &HandlerOptions{MaxLogs: 4})
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testTakeN(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testTakeN(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testTakeN(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(4)})
+	})
+	t.Run("not Truncater", func(t *testing.T) {
+		testTakeN(t, &HandlerOptions{ObservedLogs: plainLogs{NewObservedLogsDefault(4)}})
+	})
+}
+
+func testTakeN(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	messages := func(records []LoggedRecord) []string {
+		res := make([]string, 0, len(records))
+		for _, r := range records {
+			res = append(res, r.Record.Message)
+		}
+		return res
+	}
+
+	assert.Equal(t, []LoggedRecord{}, TakeN(logs, 1))
+
+	// fixed size collections of 4 wrap and keep only "log 2" .. "log 5"
+	for i := 0; i < 6; i++ {
+		logger.Info(fmt.Sprintf("log %d", i))
+	}
+	all := Messages(logs)
+
+	assert.Equal(t, []LoggedRecord{}, TakeN(logs, 0))
+	assert.Equal(t, []LoggedRecord{}, TakeN(logs, -1))
+	assert.Equal(t, all[:2], messages(TakeN(logs, 2)))
+	assert.Equal(t, all[2:], Messages(logs))
+
+	logger.Info("log 6")
+	logger.Info("log 7")
+	logger.Info("log 8")
+
+	want := append(all[2:], "log 6", "log 7", "log 8")
+	want = want[len(want)-logs.Len():]
+	assert.Equal(t, want, Messages(logs))
+
+	assert.Equal(t, want[:1], messages(TakeN(logs, 1)))
+	assert.Equal(t, want[1:], messages(TakeN(logs, 100)))
+	assertEmpty(t, logs)
+}
+
+func TestTakeNConcurrent(t *testing.T) {
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testTakeNConcurrent(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testTakeNConcurrent(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+}
+
+func testTakeNConcurrent(t *testing.T, ho *HandlerOptions) {
+	const total = 1000
+
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < total; i++ {
+			logger.Info("log", slog.Int("i", i))
+		}
+	}()
+
+	var received []int64
+	consume := func() {
+		for _, r := range TakeN(logs, 7) {
+			received = append(received, r.AttrsMap()["i"].(int64))
+		}
+	}
+
+	for {
+		select {
+		case <-done:
+			for logs.Len() > 0 {
+				consume()
+			}
+
+			require.Len(t, received, total)
+			for i := range received {
+				require.Equal(t, int64(i), received[i], "records must be taken in order and only once")
+			}
+			return
+		default:
+			consume()
+		}
+	}
+}
+
+func TestDump(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testDump(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testDump(t, NewObservedLogsDefault(0))
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testDump(t, NewObservedLogsRing(0))
+	})
+}
+
+func testDump(t *testing.T, ol ObservedLogs) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	handler, logs := New(&HandlerOptions{
+		Level:        slog.LevelDebug,
+		Now:          func() time.Time { return ts },
+		ObservedLogs: ol,
+	})
+	logger := slog.New(handler).With(slog.Int("i", 1))
+
+	logger.Info("foo")
+	logger.WithGroup("foo").With(slog.Int("i", 2)).WithGroup("bar").With(slog.Int("i", 3)).Debug("bar", slog.String("s", "with space"))
+
+	var buf bytes.Buffer
+	require.NoError(t, Dump(&buf, logs))
+	assert.Equal(t, `time=2024-01-02T03:04:05.000Z level=INFO msg=foo i=1
+time=2024-01-02T03:04:05.000Z level=DEBUG msg=bar i=1 foo.i=2 foo.bar.i=3 foo.bar.s="with space"
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, Dump(&buf, logs.FilterMessage("nope")))
+	assert.Empty(t, buf.String())
+}
+
+func TestRange(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testRange(t, nil)
+	})
+	t.Run("ObservedLogs not set fixed", func(t *testing.T) {
+		testRange(t, &HandlerOptions{MaxLogs: 4})
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testRange(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testRange(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testRange(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(4)})
+	})
+	t.Run("not Ranger", func(t *testing.T) {
+		testRange(t, &HandlerOptions{ObservedLogs: plainLogs{NewObservedLogsDefault(0)}})
+	})
+}
+
+// plainLogs hides the optional interfaces of the wrapped collection, so that only ObservedLogs methods are available.
+type plainLogs struct {
+	ObservedLogs
+}
+
+func testRange(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	Range(logs, func(LoggedRecord) bool {
+		require.FailNow(t, "empty collection must not call fn")
+		return true
+	})
+
+	// fixed size collections of 4 wrap and keep only the last 4 messages
+	for i := 0; i < 6; i++ {
+		logger.Info(fmt.Sprintf("log %d", i))
+	}
+
+	var got []string
+	Range(logs, func(r LoggedRecord) bool {
+		got = append(got, r.Record.Message)
+		return true
+	})
+	assert.Equal(t, Messages(logs), got)
+	if logs.Len() == 4 {
+		assert.Equal(t, []string{"log 2", "log 3", "log 4", "log 5"}, got)
+	}
+
+	got = nil
+	Range(logs, func(r LoggedRecord) bool {
+		got = append(got, r.Record.Message)
+		return len(got) < 2
+	})
+	assert.Equal(t, Messages(logs)[:2], got, "iteration must stop when fn returns false")
+}
+
+func TestAt(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testAt(t, nil)
+	})
+	t.Run("ObservedLogs not set fixed", func(t *testing.T) {
+		testAt(t, &HandlerOptions{MaxLogs: 4})
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testAt(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testAt(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testAt(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(4)})
+	})
+	t.Run("not Indexer", func(t *testing.T) {
+		testAt(t, &HandlerOptions{ObservedLogs: plainLogs{NewObservedLogsDefault(4)}})
+	})
+}
+
+func testAt(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	_, ok := At(logs, 0)
+	assert.False(t, ok)
+	_, ok = At(logs, -1)
+	assert.False(t, ok)
+
+	// fixed size collections of 4 wrap and keep only the last 4 messages
+	for i := 0; i < 7; i++ {
+		logger.Info(fmt.Sprintf("log %d", i))
+	}
+
+	all := logs.All()
+	for i := range all {
+		r, ok := At(logs, i)
+		require.True(t, ok)
+		assert.Equal(t, all[i], r)
+
+		r, ok = At(logs, i-len(all))
+		require.True(t, ok)
+		assert.Equal(t, all[i], r)
+	}
+
+	last, ok := At(logs, -1)
+	require.True(t, ok)
+	assert.Equal(t, "log 6", last.Record.Message)
+
+	first, ok := At(logs, 0)
+	require.True(t, ok)
+	if len(all) == 4 {
+		assert.Equal(t, "log 3", first.Record.Message)
+	} else {
+		assert.Equal(t, "log 0", first.Record.Message)
+	}
+
+	_, ok = At(logs, len(all))
+	assert.False(t, ok)
+	_, ok = At(logs, -len(all)-1)
+	assert.False(t, ok)
+}
+
+func TestRingAtWrapped(t *testing.T) {
+	// logical positions are translated to the physical slots for every position of the wrapped ring head
+	for n := 4; n <= 9; n++ {
+		ring := NewObservedLogsRing(4)
+		for i := 0; i < n; i++ {
+			ring.Add(slog.NewRecord(time.Time{}, slog.LevelInfo, fmt.Sprintf("log %d", i), 0), nil)
+		}
+
+		for i := 0; i < 4; i++ {
+			want := fmt.Sprintf("log %d", n-4+i)
+
+			r, ok := ring.At(i)
+			require.True(t, ok)
+			assert.Equal(t, want, r.Record.Message, "%d records, position %d", n, i)
+
+			r, ok = ring.At(i - 4)
+			require.True(t, ok)
+			assert.Equal(t, want, r.Record.Message, "%d records, position %d", n, i-4)
+		}
+
+		_, ok := ring.At(4)
+		assert.False(t, ok)
+		assert.Equal(t, ring.All()[2:], ring.Last(2))
+	}
+}
+
+func TestLast(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testLast(t, nil)
+	})
+	t.Run("ObservedLogs not set fixed", func(t *testing.T) {
+		testLast(t, &HandlerOptions{MaxLogs: 4})
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testLast(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testLast(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testLast(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(4)})
+	})
+	t.Run("not Indexer", func(t *testing.T) {
+		testLast(t, &HandlerOptions{ObservedLogs: plainLogs{NewObservedLogsDefault(4)}})
+	})
+}
+
+func testLast(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	assert.Empty(t, Last(logs, 2))
+	_, ok := Latest(logs)
+	assert.False(t, ok)
+
+	// fixed size collections of 4 wrap and keep only the last 4 messages
+	for i := 0; i < 7; i++ {
+		logger.Info(fmt.Sprintf("log %d", i))
+	}
+
+	messages := func(records []LoggedRecord) []string {
+		res := make([]string, 0, len(records))
+		for _, r := range records {
+			res = append(res, r.Record.Message)
+		}
+		return res
+	}
+
+	assert.Equal(t, []string{"log 4", "log 5", "log 6"}, messages(Last(logs, 3)))
+	assert.Equal(t, []string{"log 6"}, messages(Last(logs, 1)))
+	assert.Empty(t, Last(logs, 0))
+	assert.Empty(t, Last(logs, -1))
+	assert.Equal(t, logs.All(), Last(logs, 100))
+
+	latest, ok := Latest(logs)
+	require.True(t, ok)
+	assert.Equal(t, "log 6", latest.Record.Message)
+}
+
+func TestGroupBy(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testGroupBy(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testGroupBy(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testGroupBy(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testGroupBy(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(5)})
+	})
+}
+
+func testGroupBy(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	assert.Empty(t, GroupByMessage(logs))
+	assert.Empty(t, GroupByLevel(logs))
+
+	// fixed size collection of 5 wraps and drops the first record
+	logger.Info("dropped")
+	logger.Info("retry", slog.Int("i", 1))
+	logger.Warn("retry", slog.Int("i", 2))
+	logger.Info("done", slog.Int("i", 3))
+	logger.Error("retry", slog.Int("i", 4))
+	logger.Info("done", slog.Int("i", 5))
+
+	attrsMaps := func(records []LoggedRecord) []map[string]any {
+		res := make([]map[string]any, 0, len(records))
+		for _, r := range records {
+			res = append(res, r.AttrsMap())
+		}
+		return res
+	}
+
+	byMessage := GroupByMessage(logs)
+	delete(byMessage, "dropped")
+	require.Len(t, byMessage, 2)
+	assert.Equal(t, []map[string]any{{"i": int64(1)}, {"i": int64(2)}, {"i": int64(4)}}, attrsMaps(byMessage["retry"]))
+	assert.Equal(t, []map[string]any{{"i": int64(3)}, {"i": int64(5)}}, attrsMaps(byMessage["done"]))
+
+	byLevel := GroupByLevel(logs)
+	require.Len(t, byLevel, 3)
+	assert.Equal(t, []map[string]any{{"i": int64(2)}}, attrsMaps(byLevel[slog.LevelWarn]))
+	assert.Equal(t, []map[string]any{{"i": int64(4)}}, attrsMaps(byLevel[slog.LevelError]))
+	info := attrsMaps(byLevel[slog.LevelInfo])
+	assert.Equal(t, []map[string]any{{"i": int64(1)}, {"i": int64(3)}, {"i": int64(5)}}, info[len(info)-3:])
+
+	// returned records are copies
+	byLevel[slog.LevelWarn][0].Record.Message = "modified"
+	assert.Equal(t, 0, CountMessage(logs, "modified"))
+}
+
+func TestMaxBytes(t *testing.T) {
+	// every record is 10 bytes: 3 bytes message, 1 byte key and 6 bytes string value
+	payload := func(i int) slog.Attr {
+		return slog.String("p", fmt.Sprintf("%06d", i))
+	}
+
+	t.Run("MaxBytes", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{MaxBytes: 35})
+		logger := slog.New(handler)
+
+		for i := 0; i < 10; i++ {
+			logger.Info("log", payload(i))
+		}
+		require.Equal(t, 3, logs.Len())
+		assert.Equal(t, []map[string]any{{"p": "000007"}, {"p": "000008"}, {"p": "000009"}}, AttrsMaps(logs))
+
+		// huge record evicts everything else, but is kept itself
+		logger.Info("log", slog.String("p", strings.Repeat("x", 100)))
+		require.Equal(t, 1, logs.Len())
+
+		logger.Info("log", payload(10))
+		assert.Equal(t, []map[string]any{{"p": "000010"}}, AttrsMaps(logs))
+
+		TakeN(logs, 1)
+		for i := 11; i < 15; i++ {
+			logger.Info("log", payload(i))
+		}
+		assert.Equal(t, []map[string]any{{"p": "000012"}, {"p": "000013"}, {"p": "000014"}}, AttrsMaps(logs))
+	})
+
+	t.Run("MaxBytes and MaxLogs", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{MaxBytes: 35, MaxLogs: 2})
+		logger := slog.New(handler)
+
+		for i := 0; i < 10; i++ {
+			logger.Info("log", payload(i))
+		}
+		assert.Equal(t, []map[string]any{{"p": "000008"}, {"p": "000009"}}, AttrsMaps(logs), "MaxLogs hits first")
+
+		logger.Info("log", slog.String("p", strings.Repeat("x", 30)))
+		assert.Equal(t, []map[string]any{{"p": strings.Repeat("x", 30)}}, AttrsMaps(logs), "MaxBytes hits first")
+	})
+
+	t.Run("mutated values", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{MaxBytes: 35})
+		logger := slog.New(handler)
+
+		m := map[string]string{"k": "v"}
+		logger.Info("log", slog.Any("m", m))
+		m["k"] = strings.Repeat("x", 100)
+
+		for i := 0; i < 10; i++ {
+			logger.Info("log", payload(i))
+		}
+		assert.Equal(t, 3, logs.Len(), "size is estimated once, when the record is added")
+	})
+
+	t.Run("values logging when formatted", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{MaxBytes: 100})
+		logger := slog.New(handler)
+
+		logger.Info("log", slog.Any("v", selfLogging{logger: logger}))
+		assert.Equal(t, []string{"formatted", "log"}, Messages(logs))
+	})
+}
+
+// selfLogging logs when it is formatted, e.g. when the size of the record it is logged with is estimated.
+type selfLogging struct {
+	logger *slog.Logger
+}
+
+func (s selfLogging) String() string {
+	s.logger.Info("formatted")
+	return "self"
+}
+
+func TestPartition(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testPartition(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testPartition(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testPartition(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testPartition(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(5)})
+	})
+	t.Run("not Ranger", func(t *testing.T) {
+		testPartition(t, &HandlerOptions{ObservedLogs: plainLogs{NewObservedLogsDefault(0)}})
+	})
+}
+
+func testPartition(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	// fixed size collection of 5 wraps and drops the first 2 records
+	for i := 0; i < 7; i++ {
+		if i%3 == 0 {
+			logger.Error(fmt.Sprintf("error %d", i))
+		} else {
+			logger.Info(fmt.Sprintf("info %d", i))
+		}
+	}
+
+	isError := func(r LoggedRecord) bool {
+		return r.Record.Level == slog.LevelError
+	}
+	var calls int
+	errs, rest := Partition(logs, func(r LoggedRecord) bool {
+		calls++
+		return isError(r)
+	})
+
+	assert.Equal(t, logs.Len(), calls, "the function is expected to be called once for each record")
+	assert.Equal(t, logs.Len(), errs.Len()+rest.Len())
+	assert.Equal(t, Messages(logs.Filter(isError)), Messages(errs))
+	assert.Equal(t, Messages(logs.Filter(func(r LoggedRecord) bool { return !isError(r) })), Messages(rest))
+
+	// collections are independent
+	errs.Add(slog.NewRecord(time.Now(), slog.LevelError, "added", 0), nil)
+	assert.Equal(t, 0, CountMessage(rest, "added"))
+	assert.Equal(t, 0, CountMessage(logs, "added"))
+}
+
+func TestOnEvict(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts *HandlerOptions
+		want []string
+	}{
+		"MaxLogs": {
+			opts: &HandlerOptions{MaxLogs: 3},
+			want: []string{"log 0", "log 1"},
+		},
+		"MaxBytes": {
+			// every record is 5 bytes
+			opts: &HandlerOptions{MaxBytes: 12},
+			want: []string{"log 0", "log 1", "log 2"},
+		},
+		"ObservedLogsDefault fixed": {
+			opts: &HandlerOptions{ObservedLogs: NewObservedLogsDefault(3)},
+			want: []string{"log 0", "log 1"},
+		},
+		"ObservedLogsRing": {
+			opts: &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)},
+		},
+		"ObservedLogsRing fixed": {
+			opts: &HandlerOptions{ObservedLogs: NewObservedLogsRing(3)},
+			want: []string{"log 0", "log 1"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var (
+				evicted []string
+				logs    ObservedLogs
+			)
+			tc.opts.OnEvict = func(r LoggedRecord) {
+				// callback is called outside of the lock, so the collection can be used from it
+				assert.NotZero(t, logs.Len())
+				evicted = append(evicted, r.Record.Message)
+			}
+
+			handler, logs := New(tc.opts)
+			logger := slog.New(handler)
+			for i := 0; i < 5; i++ {
+				logger.Info(fmt.Sprintf("log %d", i))
+			}
+
+			assert.Equal(t, tc.want, evicted)
+			assert.Equal(t, 5-len(tc.want), logs.Len())
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testContains(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testContains(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testContains(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testContains(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(2)})
+	})
+}
+
+func testContains(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	assert.False(t, Contains(logs, "server started"))
+
+	// fixed size collection of 2 drops the first record
+	logger.Info("server starting", slog.Int("port", 8080))
+	logger.Info("server started", slog.Group("http", slog.Int("port", 8080)))
+	logger.Info("request served", slog.String("path", "/"))
+
+	assert.True(t, Contains(logs, "server started"))
+	assert.False(t, Contains(logs, "server"))
+	assert.True(t, ContainsSnippet(logs, "server"))
+	assert.True(t, ContainsSnippet(logs, "served"))
+	assert.False(t, ContainsSnippet(logs, "stopped"))
+	assert.True(t, ContainsAttr(logs, slog.Int("port", 8080)))
+	assert.True(t, ContainsAttr(logs, slog.String("path", "/")))
+	assert.False(t, ContainsAttr(logs, slog.String("path", "/health")))
+
+	if logs.Len() == 2 {
+		assert.False(t, Contains(logs, "server starting"))
+	} else {
+		assert.True(t, Contains(logs, "server starting"))
+	}
+
+	attr := slog.String("path", "/")
+	allocs := testing.AllocsPerRun(10, func() {
+		Contains(logs, "request served")
+		ContainsSnippet(logs, "served")
+		ContainsAttr(logs, attr)
+	})
+	assert.Zero(t, allocs, "lookups must not allocate")
+}
+
+func TestCountAnyNone(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testCountAnyNone(t, &HandlerOptions{Level: slog.LevelDebug})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testCountAnyNone(t, &HandlerOptions{Level: slog.LevelDebug, ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testCountAnyNone(t, &HandlerOptions{Level: slog.LevelDebug, ObservedLogs: NewObservedLogsRing(4)})
+	})
+	t.Run("not collection of this package", func(t *testing.T) {
+		testCountAnyNone(t, &HandlerOptions{Level: slog.LevelDebug, ObservedLogs: plainLogs{NewObservedLogsDefault(0)}})
+	})
+}
+
+func testCountAnyNone(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	isError := func(r LoggedRecord) bool {
+		return r.Record.Level == slog.LevelError && strings.Contains(r.Record.Message, "query")
+	}
+	assert.Equal(t, 0, Count(logs, isError))
+	assert.False(t, Any(logs, isError))
+	assert.True(t, None(logs, isError))
+
+	logger.Debug("query started")
+	logger.Error("query failed")
+	logger.Info("query retried")
+	logger.Error("query failed")
+
+	assert.Equal(t, logs.FilterMessageSnippet("query").FilterLevelExact(slog.LevelError).Len(), Count(logs, isError))
+	assert.Equal(t, 2, Count(logs, isError))
+	assert.True(t, Any(logs, isError))
+	assert.False(t, None(logs, isError))
+
+	var calls int
+	assert.True(t, Any(logs, func(r LoggedRecord) bool {
+		calls++
+		return r.Record.Level >= slog.LevelError
+	}))
+	assert.Equal(t, 2, calls, "Any is expected to stop at the first match")
+}
+
+func BenchmarkContains(b *testing.B) {
+	b.Run("ObservedLogsDefault", func(b *testing.B) {
+		// BenchmarkContains/ObservedLogsDefault         	  355100	      3520 ns/op	       0 B/op	       0 allocs/op
+		benchmarkContains(b, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	b.Run("ObservedLogsRing", func(b *testing.B) {
+		// BenchmarkContains/ObservedLogsRing            	  286280	      4387 ns/op	       0 B/op	       0 allocs/op
+		benchmarkContains(b, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	b.Run("ObservedLogsRing fixed", func(b *testing.B) {
+		// BenchmarkContains/ObservedLogsRing_fixed      	  546028	      2156 ns/op	       0 B/op	       0 allocs/op
+		benchmarkContains(b, &HandlerOptions{ObservedLogs: NewObservedLogsRing(50)})
+	})
+}
+
+func benchmarkContains(b *testing.B, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+	for i := 0; i < 100; i++ {
+		logger.Info("log", slog.Int("i", i))
+	}
+	// message and snippet hit the first record, attribute is in the last one
+	attr := slog.Int("i", 99)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Contains(logs, "log")
+		ContainsSnippet(logs, "lo")
+		ContainsAttr(logs, attr)
+	}
+}
+
+func TestFilterByTime(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testFilterByTime(t, &HandlerOptions{})
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testFilterByTime(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testFilterByTime(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testFilterByTime(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(5)})
+	})
+}
+
+func testFilterByTime(t *testing.T, ho *HandlerOptions) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	ho.Now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	// records are logged at start+1s ... start+5s
+	for i := 1; i <= 5; i++ {
+		logger.Info(fmt.Sprintf("log %d", i))
+	}
+
+	at := func(sec int) time.Time {
+		return start.Add(time.Duration(sec) * time.Second)
+	}
+	assert.Equal(t, []string{"log 2", "log 3", "log 4"}, Messages(FilterByTime(logs, at(2), at(4))))
+	assert.Equal(t, []string{"log 3"}, Messages(FilterByTime(logs, at(3), at(3))))
+	assert.Equal(t, []string{"log 4", "log 5"}, Messages(FilterByTime(logs, at(4), time.Time{})))
+	assert.Equal(t, []string{"log 1", "log 2"}, Messages(FilterByTime(logs, time.Time{}, at(2))))
+	assert.Equal(t, Messages(logs), Messages(FilterByTime(logs, time.Time{}, time.Time{})))
+	assertEmpty(t, FilterByTime(logs, at(6), time.Time{}))
+}
+
+func TestSortedByTimeAndReverse(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testSortedByTimeAndReverse(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testSortedByTimeAndReverse(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testSortedByTimeAndReverse(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testSortedByTimeAndReverse(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(4)})
+	})
+}
+
+func testSortedByTimeAndReverse(t *testing.T, ho *HandlerOptions) {
+	_, logs := New(ho)
+
+	// records are added out of time order, as it happens with concurrent loggers,
+	// fixed size collection of 4 drops the first record
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, sec := range []int{0, 3, 1, 2, 1} {
+		logs.Add(slog.NewRecord(start.Add(time.Duration(sec)*time.Second), slog.LevelInfo, fmt.Sprintf("log %d", i), 0), nil)
+	}
+
+	messages := Messages(logs)
+	sorted := SortedByTime(logs)
+	reversed := Reverse(logs)
+
+	if logs.Len() == 4 {
+		assert.Equal(t, []string{"log 2", "log 4", "log 3", "log 1"}, Messages(sorted))
+		assert.Equal(t, []string{"log 4", "log 3", "log 2", "log 1"}, Messages(reversed))
+	} else {
+		assert.Equal(t, []string{"log 0", "log 2", "log 4", "log 3", "log 1"}, Messages(sorted))
+		assert.Equal(t, []string{"log 4", "log 3", "log 2", "log 1", "log 0"}, Messages(reversed))
+	}
+
+	// original collection is not modified and copies are independent
+	assert.Equal(t, messages, Messages(logs))
+	sorted.Add(slog.NewRecord(start, slog.LevelInfo, "added", 0), nil)
+	assert.False(t, Contains(logs, "added"))
+	assert.False(t, Contains(reversed, "added"))
+}
+
+func TestRingFilterChain(t *testing.T) {
+	handler, logs := New(&HandlerOptions{ObservedLogs: NewObservedLogsRing(5)})
+	logger := slog.New(handler)
+
+	// ring of 5 wraps twice and keeps only "log 7" ... "log 11"
+	for i := 0; i < 12; i++ {
+		if i%2 == 0 {
+			logger.Warn(fmt.Sprintf("log %d", i), slog.Int("i", i))
+		} else {
+			logger.Info(fmt.Sprintf("log %d", i), slog.Int("i", i))
+		}
+	}
+	require.Equal(t, 5, logs.Len())
+
+	warns := logs.FilterLevelExact(slog.LevelWarn)
+	assert.Equal(t, []string{"log 8", "log 10"}, Messages(warns))
+
+	chained := FilterAttrValue(warns, "i", func(v slog.Value) bool { return v.Int64() > 8 })
+	assert.Equal(t, 1, chained.Len())
+	assert.Equal(t, []string{"log 10"}, Messages(chained))
+	last, ok := At(chained, -1)
+	require.True(t, ok)
+	assert.Equal(t, "log 10", last.Record.Message)
+
+	chained = logs.FilterMessageSnippet("log 1").FilterLevelExact(slog.LevelInfo)
+	assert.Equal(t, []string{"log 11"}, Messages(chained))
+
+	// derived collections behave as independent unbounded collections
+	cursor := warns.(CursorReader).Cursor()
+	warns.Add(slog.NewRecord(time.Time{}, slog.LevelWarn, "added", 0), nil)
+	since, _, dropped := warns.(CursorReader).AllSince(cursor)
+	assert.Zero(t, dropped)
+	require.Len(t, since, 1)
+	assert.Equal(t, "added", since[0].Record.Message)
+	assert.Equal(t, []string{"log 8", "log 10", "added"}, Messages(warns))
+	assert.Equal(t, 5, logs.Len())
+
+	taken := warns.TakeAll()
+	require.Len(t, taken, 3)
+	assertEmpty(t, warns)
+	assert.Equal(t, []string{"log 7", "log 8", "log 9", "log 10", "log 11"}, Messages(logs))
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testSnapshot(t, &HandlerOptions{MaxLogs: 10})
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testSnapshot(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testSnapshot(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testSnapshot(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(10)})
+	})
+	t.Run("not Snapshotter", func(t *testing.T) {
+		testSnapshot(t, &HandlerOptions{ObservedLogs: plainLogs{NewObservedLogsDefault(0)}})
+	})
+}
+
+func testSnapshot(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	for i := 0; i < 20; i++ {
+		logger.Info("log", slog.Int("i", i))
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 20; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				logger.Info("log", slog.Int("i", i))
+			}
+		}
+	}()
+
+	snapshot := Snapshot(logs)
+	want := snapshot.All()
+	require.NotEmpty(t, want)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, want, snapshot.All())
+		assert.Equal(t, len(want), snapshot.FilterMessage("log").Len())
+		assert.Len(t, AttrsMaps(snapshot), len(want))
+	}
+	close(done)
+	wg.Wait()
+
+	assert.PanicsWithValue(t, "observer: Add called on a read-only snapshot", func() {
+		snapshot.Add(slog.NewRecord(time.Time{}, slog.LevelInfo, "added", 0), nil)
+	})
+	assert.Panics(t, func() { snapshot.TakeAll() })
+	assert.Panics(t, func() { TakeN(snapshot, 1) })
+	assert.Panics(t, func() { Reset(snapshot) })
+	assert.Equal(t, want, snapshot.All())
+
+	// derived collections are writable
+	filtered := snapshot.FilterMessage("log")
+	assert.NotPanics(t, func() { filtered.TakeAll() })
+}
+
+func TestClone(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testClone(t, &HandlerOptions{}, 0)
+	})
+	t.Run("ObservedLogs not set fixed", func(t *testing.T) {
+		testClone(t, &HandlerOptions{MaxLogs: 3}, 3)
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testClone(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)}, 0)
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testClone(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(3)}, 3)
+	})
+	t.Run("not Cloner", func(t *testing.T) {
+		l := NewObservedLogsDefault(3)
+		testClone(t, &HandlerOptions{ObservedLogs: struct {
+			plainLogs
+			Limiter
+		}{plainLogs{l}, l}}, 3)
+	})
+}
+
+func testClone(t *testing.T, ho *HandlerOptions, maxLogs int) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	for i := 0; i < 5; i++ {
+		logger.Info("log", slog.Int("i", i))
+	}
+
+	clone := Clone(logs)
+	want := logs.All()
+	assert.Equal(t, want, clone.All())
+
+	// the clone is not affected by the original collection changes
+	logger.Info("log", slog.Int("i", 5))
+	TakeN(logs, 1)
+	assert.Equal(t, want, clone.All())
+
+	// the clone is writable and keeps the capacity of the original collection
+	for i := 10; i < 15; i++ {
+		clone.Add(slog.NewRecord(time.Time{}, slog.LevelInfo, "clone", 0), []slog.Attr{slog.Int("i", i)})
+	}
+	if maxLogs > 0 {
+		assert.Equal(t, maxLogs, clone.Len())
+		assert.Equal(t, []string{"clone", "clone", "clone"}, Messages(clone))
+	} else {
+		assert.Equal(t, len(want)+5, clone.Len())
+	}
+	assert.Equal(t, 0, CountMessage(logs, "clone"))
+}
+
+func TestAddRecords(t *testing.T) {
+	fixture := make([]LoggedRecord, 0, 5)
+	for i := 0; i < 5; i++ {
+		fixture = append(fixture, LoggedRecord{
+			Record: slog.NewRecord(time.Time{}, slog.LevelInfo, "fixture", 0),
+			Attrs:  []slog.Attr{slog.Int("i", i)},
+		})
+	}
+
+	for name, tc := range map[string]struct {
+		logs ObservedLogs
+		want []map[string]any
+	}{
+		"ObservedLogsDefault": {
+			logs: NewObservedLogsDefault(0),
+			want: []map[string]any{{"i": int64(0)}, {"i": int64(1)}, {"i": int64(2)}, {"i": int64(3)}, {"i": int64(4)}},
+		},
+		"ObservedLogsDefault fixed": {
+			logs: NewObservedLogsDefault(3),
+			want: []map[string]any{{"i": int64(2)}, {"i": int64(3)}, {"i": int64(4)}},
+		},
+		"ObservedLogsRing": {
+			logs: NewObservedLogsRing(0),
+			want: []map[string]any{{"i": int64(0)}, {"i": int64(1)}, {"i": int64(2)}, {"i": int64(3)}, {"i": int64(4)}},
+		},
+		"ObservedLogsRing fixed": {
+			logs: NewObservedLogsRing(3),
+			want: []map[string]any{{"i": int64(2)}, {"i": int64(3)}, {"i": int64(4)}},
+		},
+		"not RecordsAdder": {
+			logs: plainLogs{NewObservedLogsDefault(0)},
+			want: []map[string]any{{"i": int64(0)}, {"i": int64(1)}, {"i": int64(2)}, {"i": int64(3)}, {"i": int64(4)}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var evicted []int64
+			_, logs := New(&HandlerOptions{ObservedLogs: tc.logs, OnEvict: func(r LoggedRecord) {
+				evicted = append(evicted, r.AttrsMap()["i"].(int64))
+			}})
+
+			AddRecords(logs, fixture)
+			assert.Equal(t, tc.want, AttrsMaps(logs))
+			assert.Equal(t, uint64(len(fixture)), Total(logs))
+			assert.Len(t, evicted, len(fixture)-len(tc.want))
+			assert.Equal(t, len(tc.want), logs.FilterMessage("fixture").Len())
+
+			assert.Panics(t, func() { AddRecords(Snapshot(logs), fixture) })
+		})
+	}
+}
+
+func TestCapBounded(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts    *HandlerOptions
+		cap     int
+		bounded bool
+	}{
+		"ObservedLogs not set":           {opts: nil},
+		"ObservedLogs not set fixed":     {opts: &HandlerOptions{MaxLogs: 5}, cap: 5, bounded: true},
+		"ObservedLogs not set max bytes": {opts: &HandlerOptions{MaxBytes: 100}, bounded: true},
+		"ObservedLogsDefault":            {opts: &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)}},
+		"ObservedLogsDefault fixed":      {opts: &HandlerOptions{ObservedLogs: NewObservedLogsDefault(3)}, cap: 3, bounded: true},
+		"ObservedLogsRing":               {opts: &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)}},
+		"ObservedLogsRing fixed":         {opts: &HandlerOptions{ObservedLogs: NewObservedLogsRing(3)}, cap: 3, bounded: true},
+		"not Limiter":                    {opts: &HandlerOptions{ObservedLogs: plainLogs{NewObservedLogsDefault(3)}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler, logs := New(tc.opts)
+			logger := slog.New(handler)
+
+			for i := 0; i < 10; i++ {
+				logger.Info("log")
+			}
+			logs.TakeAll()
+
+			assert.Equal(t, tc.cap, Cap(logs))
+			assert.Equal(t, tc.bounded, Bounded(logs))
+			assert.Equal(t, tc.cap, Cap(Clone(logs)))
+
+			// derived collections are unbounded
+			assert.Equal(t, 0, Cap(logs.FilterMessage("log")))
+			assert.False(t, Bounded(logs.FilterMessage("log")))
+		})
+	}
+}
+
+func TestObservedLogsRingOrder(t *testing.T) {
+	const capacity = 4
+
+	for _, n := range []int{capacity - 1, capacity, capacity + 1, 2*capacity - 1, 2 * capacity, 2*capacity + 1, 3 * capacity} {
+		t.Run(fmt.Sprintf("%d records", n), func(t *testing.T) {
+			handler, logs := New(&HandlerOptions{ObservedLogs: NewObservedLogsRing(capacity)})
+			logger := slog.New(handler)
+
+			// several cycles with TakeAll in between must behave the same way
+			for cycle := 0; cycle < 3; cycle++ {
+				for i := 0; i < n; i++ {
+					logger.Info(fmt.Sprintf("log %d", i))
+				}
+
+				want := make([]string, 0, capacity)
+				for i := max(0, n-capacity); i < n; i++ {
+					want = append(want, fmt.Sprintf("log %d", i))
+				}
+
+				assert.Equal(t, want, Messages(logs))
+
+				var got []string
+				for _, r := range logs.All() {
+					got = append(got, r.Record.Message)
+				}
+				assert.Equal(t, want, got, "All, cycle %d", cycle)
+
+				got = nil
+				for _, r := range logs.TakeAll() {
+					got = append(got, r.Record.Message)
+				}
+				assert.Equal(t, want, got, "TakeAll, cycle %d", cycle)
+				assert.Equal(t, 0, logs.Len())
+			}
+		})
+	}
+}
+
+func TestObservedLogsRingPartlyFilled(t *testing.T) {
+	// fixed size ring that has not wrapped yet must not expose the empty slots
+	logs := NewObservedLogsRing(5)
+	handler, _ := New(&HandlerOptions{ObservedLogs: logs})
+	logger := slog.New(handler)
+
+	logger.Info("log 0")
+	logger.Info("log 1")
+
+	assert.Equal(t, 2, logs.Len())
+	assert.Equal(t, []string{"log 0", "log 1"}, Messages(logs))
+	assert.Equal(t, []LoggedRecord{
+		{Record: slog.Record{Level: slog.LevelInfo, Message: "log 0"}},
+		{Record: slog.Record{Level: slog.LevelInfo, Message: "log 1"}},
+	}, logs.AllUntimed())
+
+	all := logs.Filter(func(LoggedRecord) bool { return true })
+	assert.Equal(t, 2, all.Len())
+	assert.Equal(t, logs.All(), all.All())
+}
+
+func TestDropped(t *testing.T) {
+	const maxLogs = 5
+
+	for name, tc := range map[string]struct {
+		opts *HandlerOptions
+		want uint64
+	}{
+		"ObservedLogs not set": {
+			opts: &HandlerOptions{},
+		},
+		"ObservedLogs not set fixed": {
+			opts: &HandlerOptions{MaxLogs: maxLogs},
+			want: maxLogs,
+		},
+		"ObservedLogs not set MaxBytes": {
+			// every record is 5 bytes, so only 5 records fit
+			opts: &HandlerOptions{MaxBytes: 5 * maxLogs},
+			want: maxLogs,
+		},
+		"ObservedLogsRing": {
+			opts: &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)},
+		},
+		"ObservedLogsRing fixed": {
+			opts: &HandlerOptions{ObservedLogs: NewObservedLogsRing(maxLogs)},
+			want: maxLogs,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler, logs := New(tc.opts)
+			logger := slog.New(handler)
+
+			dc, ok := logs.(DroppedCounter)
+			require.True(t, ok)
+
+			for i := 0; i < 2*maxLogs; i++ {
+				logger.Info(fmt.Sprintf("log %d", i))
+			}
+			assert.Equal(t, tc.want, dc.Dropped())
+
+			// counter is not reset when the collection is truncated
+			logs.TakeAll()
+			assert.Equal(t, tc.want, dc.Dropped())
+		})
+	}
+}
+
+func TestTotal(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testTotal(t, &HandlerOptions{MaxLogs: 3})
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testTotal(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(3)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testTotal(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(3)})
+	})
+	t.Run("not TotalCounter", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{ObservedLogs: plainLogs{NewObservedLogsDefault(3)}})
+		logger := slog.New(handler)
+
+		for i := 0; i < 10; i++ {
+			logger.Info("retry", slog.Int("attempt", i))
+		}
+		assert.Equal(t, uint64(3), Total(logs), "the number of records in the collection is expected")
+	})
+}
+
+func testTotal(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	for i := 0; i < 10; i++ {
+		logger.Info("retry", slog.Int("attempt", i))
+	}
+	assert.Equal(t, 3, logs.Len())
+	assert.Equal(t, uint64(10), Total(logs))
+
+	TakeN(logs, 1)
+	Reset(logs)
+	logger.Info("retry", slog.Int("attempt", 10))
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, uint64(11), Total(logs), "Total must not be reset by truncating the collection")
+
+	assert.Equal(t, uint64(1), Total(logs.FilterMessage("retry")))
+}
+
+func TestTotalConcurrent(t *testing.T) {
+	handler, logs := New(&HandlerOptions{MaxLogs: 3})
+	logger := slog.New(handler)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Info("log")
+				_ = Total(logs)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, uint64(400), Total(logs))
+}
+
+func TestSlogtest(t *testing.T) {
+	handler, logs := New(nil)
+
+	err := slogtest.TestHandler(handler, func() []map[string]any {
+		records := logs.TakeAll()
+		res := make([]map[string]any, 0, len(records))
+		for _, r := range records {
+			res = append(res, r.Map())
+		}
+		return res
+	})
+	require.NoError(t, err)
+}
+
+func TestSlogtestAttrsMap(t *testing.T) {
+	handler, logs := New(nil)
+
+	err := slogtest.TestHandler(handler, func() []map[string]any {
+		records := logs.TakeAll()
+		res := make([]map[string]any, 0, len(records))
+		for _, r := range records {
+			m := r.AttrsMap()
+			if !r.Record.Time.IsZero() {
+				m[slog.TimeKey] = r.Record.Time
+			}
+			m[slog.LevelKey] = r.Record.Level
+			m[slog.MessageKey] = r.Record.Message
+			res = append(res, m)
+		}
+		return res
+	})
+	require.NoError(t, err)
+}
+
+func TestJSONHandlerFidelity(t *testing.T) {
+	for name, chain := range map[string]func(l *slog.Logger) *slog.Logger{
+		"With only": func(l *slog.Logger) *slog.Logger {
+			return l.With("a", 1).With("b", 2)
+		},
+		"WithGroup only": func(l *slog.Logger) *slog.Logger {
+			return l.WithGroup("g1").WithGroup("g2")
+		},
+		"With WithGroup With": func(l *slog.Logger) *slog.Logger {
+			return l.With("a", 1).WithGroup("g1").With("b", 2)
+		},
+		"interleaved": func(l *slog.Logger) *slog.Logger {
+			return l.With("a", 1).WithGroup("g1").With("b", 2).WithGroup("g2").With("c", 3)
+		},
+		"interleaved with trailing WithGroup": func(l *slog.Logger) *slog.Logger {
+			return l.With("a", 1).WithGroup("g1").With("b", 2).WithGroup("g2").With("c", 3).WithGroup("g3")
+		},
+		"WithGroup With WithGroup": func(l *slog.Logger) *slog.Logger {
+			return l.WithGroup("g1").With("a", 1).WithGroup("g2")
+		},
+		"groups in With": func(l *slog.Logger) *slog.Logger {
+			return l.With(slog.Group("a", "b", 1)).WithGroup("g1").With(slog.Group("", "c", 2), slog.Group("d"))
+		},
+		"same keys on different levels": func(l *slog.Logger) *slog.Logger {
+			return l.With("a", 1).WithGroup("a").With("a", 2).WithGroup("a").With("a", 3)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler, logs := New(&HandlerOptions{Next: slog.NewJSONHandler(&buf, nil)})
+			logger := chain(slog.New(handler))
+
+			logger.Info("no attrs")
+			logger.Info("attrs", "x", 1, slog.Group("y", "z", 2))
+			logger.Info("empty attrs", slog.Group("y"), slog.Attr{})
+
+			records := logs.All()
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, len(records))
+
+			for i, r := range records {
+				var want map[string]any
+				require.NoError(t, json.Unmarshal([]byte(lines[i]), &want))
+				delete(want, slog.TimeKey)
+				delete(want, slog.LevelKey)
+				delete(want, slog.MessageKey)
+
+				gotJSON, err := json.Marshal(r.AttrsMap())
+				require.NoError(t, err)
+				var got map[string]any
+				require.NoError(t, json.Unmarshal(gotJSON, &got))
+
+				assert.Equal(t, want, got, "record %q, JSONHandler output: %s", r.Record.Message, lines[i])
+			}
+		})
+	}
+}
+
+func TestAddSeq(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testAddSeq(t, &HandlerOptions{AddSeq: true})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testAddSeq(t, &HandlerOptions{AddSeq: true, ObservedLogs: NewObservedLogsRing(0)})
+	})
+
+	t.Run("ObservedLogsChannel", func(t *testing.T) {
+		ch := make(chan LoggedRecord, 2)
+		handler, _ := New(&HandlerOptions{AddSeq: true, ObservedLogs: NewObservedLogsChannel(ch, true)})
+		logger := slog.New(handler)
+
+		logger.Info("first")
+		logger.Info("second")
+		assert.Equal(t, uint64(1), (<-ch).Seq)
+		assert.Equal(t, uint64(2), (<-ch).Seq)
+	})
+
+	t.Run("not set", func(t *testing.T) {
+		handler, logs := New(nil)
+		slog.New(handler).Info("log")
+		assert.Zero(t, logs.All()[0].Seq)
+	})
+}
+
+func testAddSeq(t *testing.T, ho *HandlerOptions) {
+	const goroutines, perGoroutine = 10, 100
+
+	// the same time for all the records, so that only Seq can order them
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ho.Now = func() time.Time { return ts }
+
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		g := g
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				logger.Info("log", slog.Int("g", g), slog.Int("i", i))
+			}
+		}()
+	}
+	wg.Wait()
+
+	records := logs.All()
+	require.Len(t, records, goroutines*perGoroutine)
+	for i, r := range records {
+		assert.Equal(t, uint64(i+1), r.Seq, "records are expected in the order they were added")
+	}
+
+	filtered := logs.Filter(func(r LoggedRecord) bool { return r.Record.Message == "log" }).All()
+	assert.Equal(t, records, filtered, "Filter is expected to preserve Seq")
+
+	// records of every goroutine are ordered by Seq the same way they were logged
+	last := make(map[int64]int64)
+	for _, r := range filtered {
+		m := r.AttrsMap()
+		g, i := m["g"].(int64), m["i"].(int64)
+		if prev, ok := last[g]; ok {
+			assert.Greater(t, i, prev)
+		}
+		last[g] = i
+	}
+	assert.Len(t, last, goroutines)
+
+	Reset(logs)
+	logger.Info("after reset")
+	assert.Equal(t, uint64(goroutines*perGoroutine+1), logs.All()[0].Seq, "sequence numbers are not reused")
+}
+
+func TestAddContext(t *testing.T) {
+	type ctxKey string
+	ctx := context.WithValue(context.Background(), ctxKey("request_id"), "req-1")
+
+	t.Run("set", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{AddContext: true})
+		logger := slog.New(handler)
+
+		logger.InfoContext(ctx, "request")
+		logger.Info("no context")
+
+		records := logs.All()
+		require.Len(t, records, 2)
+		assert.Equal(t, "req-1", records[0].Context().Value(ctxKey("request_id")))
+		assert.Equal(t, context.Background(), records[1].Context())
+
+		assert.Equal(t, []LoggedRecord{
+			{Record: slog.Record{Level: slog.LevelInfo, Message: "request"}},
+			{Record: slog.Record{Level: slog.LevelInfo, Message: "no context"}},
+		}, logs.AllUntimed(), "context is not compared")
+	})
+
+	t.Run("not set", func(t *testing.T) {
+		handler, logs := New(nil)
+		slog.New(handler).InfoContext(ctx, "request", slog.Int("i", 1))
+
+		assert.Equal(t, []LoggedRecord{
+			{Record: slog.Record{Level: slog.LevelInfo, Message: "request"}, Attrs: []slog.Attr{slog.Int("i", 1)}},
+		}, logs.AllUntimed(), "neither context nor extracted attributes are expected")
+	})
+}
+
+func TestTextHandlerFidelity(t *testing.T) {
+	// flatten joins the keys of the attributes in groups with dots, the same way slog.TextHandler does
+	var flatten func(res map[string]string, prefix string, m map[string]any)
+	flatten = func(res map[string]string, prefix string, m map[string]any) {
+		for k, v := range m {
+			if group, ok := v.(map[string]any); ok {
+				flatten(res, prefix+k+".", group)
+				continue
+			}
+			res[prefix+k] = fmt.Sprint(v)
+		}
+	}
+
+	tests := []struct {
+		name string
+		log  func(l *slog.Logger)
+	}{
+		{name: "WithGroup without attrs", log: func(l *slog.Logger) {
+			l.WithGroup("g").Info("msg")
+		}},
+		{name: "nested WithGroup without attrs", log: func(l *slog.Logger) {
+			l.With("a", 1).WithGroup("g1").WithGroup("g2").Info("msg")
+		}},
+		{name: "WithGroup with empty group attr", log: func(l *slog.Logger) {
+			l.WithGroup("g").Info("msg", slog.Group("empty"))
+		}},
+		{name: "inline group", log: func(l *slog.Logger) {
+			l.Info("msg", slog.Group("", slog.Int("a", 1), slog.Int("b", 2)))
+		}},
+		{name: "inline group in WithGroup", log: func(l *slog.Logger) {
+			l.WithGroup("g").Info("msg", slog.Group("", slog.Int("a", 1)))
+		}},
+		{name: "inline group in With", log: func(l *slog.Logger) {
+			l.With(slog.Group("", slog.Int("a", 1))).WithGroup("g").With(slog.Group("", slog.Int("b", 2))).Info("msg")
+		}},
+		{name: "nested inline groups", log: func(l *slog.Logger) {
+			l.Info("msg", slog.Group("g", slog.Group("", slog.Int("a", 1), slog.Group("", slog.Int("b", 2))), slog.Group("h")))
+		}},
+		{name: "inline empty group", log: func(l *slog.Logger) {
+			l.WithGroup("g").Info("msg", slog.Group(""))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler, logs := New(&HandlerOptions{Next: slog.NewTextHandler(&buf, nil)})
+			tt.log(slog.New(handler))
+
+			want := make(map[string]string)
+			for _, kv := range strings.Fields(buf.String()) {
+				k, v, _ := strings.Cut(kv, "=")
+				want[k] = v
+			}
+			delete(want, slog.TimeKey)
+			delete(want, slog.LevelKey)
+			delete(want, slog.MessageKey)
+
+			records := logs.All()
+			require.Len(t, records, 1)
+			got := make(map[string]string)
+			flatten(got, "", records[0].AttrsMap())
+
+			assert.Equal(t, want, got, "TextHandler output: %s", buf.String())
+		})
+	}
+}
+
+func TestDedup(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testDedup(t, &HandlerOptions{Dedup: true, MaxLogs: 3})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testDedup(t, &HandlerOptions{Dedup: true, ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testDedup(t, &HandlerOptions{Dedup: true, ObservedLogs: NewObservedLogsRing(3)})
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		handler, logs := New(nil)
+		logger := slog.New(handler)
+		logger.Info("retry")
+		logger.Info("retry")
+
+		assert.Equal(t, 2, logs.Len())
+		assert.Zero(t, logs.All()[0].Repeated)
+	})
+}
+
+func testDedup(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	logger.Info("connecting")
+	for i := 0; i < 100; i++ {
+		logger.Error("query failed", slog.String("err", "timeout"))
+	}
+	logger.Error("query failed", slog.String("err", "refused"))
+	logger.With(slog.String("err", "refused")).Error("query failed")
+
+	records := logs.All()
+	require.Len(t, records, 3, "repeated records must not push other records out")
+	assert.Equal(t, []string{"connecting", "query failed", "query failed"}, Messages(logs))
+	assert.Equal(t, []int{0, 99, 1}, []int{records[0].Repeated, records[1].Repeated, records[2].Repeated})
+	assert.Equal(t, "timeout", records[1].AttrsMap()["err"])
+	assert.Equal(t, uint64(103), Total(logs), "collapsed records are counted")
+
+	cursor := logs.(CursorReader).Cursor()
+	logger.Error("query failed", slog.String("err", "refused"))
+	logger.Info("connecting")
+	records = logs.All()
+	assert.Equal(t, "connecting", records[len(records)-1].Record.Message)
+	assert.Zero(t, records[len(records)-1].Repeated, "only consecutive records are collapsed")
+	assert.Equal(t, 2, records[len(records)-2].Repeated)
+
+	since, _, dropped := logs.(CursorReader).AllSince(cursor)
+	assert.Equal(t, []LoggedRecord{records[len(records)-1]}, since, "collapsed records do not move the cursor")
+	assert.Zero(t, dropped)
+}
+
+func TestOnRecord(t *testing.T) {
+	var (
+		seen    []LoggedRecord
+		logs    ObservedLogs
+		handler slog.Handler
+	)
+	handler, logs = New(&HandlerOptions{OnRecord: func(r LoggedRecord) {
+		// the record is already stored and the collection is not locked
+		latest, ok := Latest(logs)
+		assert.True(t, ok)
+		assert.Equal(t, r.Record.Message, latest.Record.Message)
+		seen = append(seen, r)
+	}})
+	logger := slog.New(handler).With(slog.Int("a", 1)).WithGroup("g")
+
+	logger.Info("first", slog.Int("b", 2))
+	logger.WithGroup("empty").Info("second")
+
+	all := logs.All()
+	require.Len(t, seen, 2)
+	assert.Equal(t, all, seen)
+	assert.Equal(t, []map[string]any{
+		{"a": int64(1), "g": map[string]any{"b": int64(2)}},
+		{"a": int64(1)},
+	}, []map[string]any{seen[0].AttrsMap(), seen[1].AttrsMap()})
+
+	t.Run("panic", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{OnRecord: func(r LoggedRecord) {
+			if r.Record.Message == "boom" {
+				panic("boom")
+			}
+		}})
+		logger := slog.New(handler)
+
+		assert.Panics(t, func() { logger.Info("boom") })
+		logger.Info("after")
+		assert.Equal(t, []string{"boom", "after"}, Messages(logs))
+	})
+}
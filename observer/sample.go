@@ -0,0 +1,66 @@
+package observer
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// SampleOptions configure the sampling of the observed records, see HandlerOptions.Sample.
+type SampleOptions struct {
+	// EveryN makes the handler keep every Nth record: the 1st, the N+1th and so on.
+	// It takes precedence over Rate. Zero or one means every record is kept.
+	EveryN uint
+	// Rate is the probability in the (0, 1] range to keep the record, the first record is always kept.
+	// Zero means it is not set, one means every record is kept.
+	Rate float64
+	// PerMessage makes the records with different messages sampled independently, so that the frequent
+	// messages do not push out the rare ones, e.g. the first record with each message is always kept.
+	PerMessage bool
+}
+
+// sampler decides whether the record is kept, it is shared between the handler and all the derived handlers.
+// Nil sampler keeps all the records.
+type sampler struct {
+	opts SampleOptions
+
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+func newSampler(opts *SampleOptions) *sampler {
+	if opts == nil || (opts.EveryN <= 1 && (opts.Rate <= 0 || opts.Rate >= 1)) {
+		return nil
+	}
+	return &sampler{opts: *opts, counts: make(map[string]uint64)}
+}
+
+// keep reports whether the record with the message is kept.
+func (s *sampler) keep(msg string) bool {
+	if s == nil {
+		return true
+	}
+
+	var key string
+	if s.opts.PerMessage {
+		key = msg
+	}
+
+	s.mu.Lock()
+	n := s.counts[key]
+	s.counts[key]++
+	s.mu.Unlock()
+
+	switch {
+	case n == 0:
+		return true
+	case s.opts.EveryN > 1:
+		return n%uint64(s.opts.EveryN) == 0
+	default:
+		return rand.Float64() < s.opts.Rate
+	}
+}
+
+// sampledOutCounter is implemented by the collections that count sampled out records as dropped.
+type sampledOutCounter interface {
+	sampledOut()
+}
@@ -0,0 +1,107 @@
+package observer
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSample(t *testing.T) {
+	t.Run("EveryN", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{Sample: &SampleOptions{EveryN: 3}})
+		logger := slog.New(handler)
+
+		for i := 0; i < 10; i++ {
+			// derived handlers share the sampling state
+			logger.With(slog.Int("i", i)).Info("log")
+		}
+
+		assert.Equal(t, []map[string]any{{"i": int64(0)}, {"i": int64(3)}, {"i": int64(6)}, {"i": int64(9)}}, AttrsMaps(logs))
+		assert.Equal(t, uint64(6), logs.(DroppedCounter).Dropped())
+	})
+
+	t.Run("PerMessage", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{Sample: &SampleOptions{EveryN: 2, PerMessage: true}})
+		logger := slog.New(handler)
+
+		for i := 0; i < 4; i++ {
+			logger.Info("frequent", slog.Int("i", i))
+			if i == 1 {
+				logger.Info("rare")
+			}
+		}
+
+		assert.Equal(t, []string{"frequent", "rare", "frequent"}, Messages(logs))
+		assert.Equal(t, []map[string]any{{"i": int64(0)}, {}, {"i": int64(2)}}, AttrsMaps(logs))
+	})
+
+	t.Run("MaxLogs", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{MaxLogs: 2, Sample: &SampleOptions{EveryN: 2}})
+		logger := slog.New(handler)
+
+		for i := 0; i < 10; i++ {
+			logger.Info("log", slog.Int("i", i))
+		}
+
+		assert.Equal(t, []map[string]any{{"i": int64(6)}, {"i": int64(8)}}, AttrsMaps(logs))
+		assert.Equal(t, uint64(5+3), logs.(DroppedCounter).Dropped(), "sampled out and evicted records are dropped")
+	})
+
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{ObservedLogs: NewObservedLogsRing(0), Sample: &SampleOptions{EveryN: 5}})
+		logger := slog.New(handler)
+
+		for i := 0; i < 10; i++ {
+			logger.Info("log", slog.Int("i", i))
+		}
+
+		assert.Equal(t, []map[string]any{{"i": int64(0)}, {"i": int64(5)}}, AttrsMaps(logs))
+		assert.Equal(t, uint64(8), logs.(DroppedCounter).Dropped())
+	})
+
+	t.Run("Next", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler, logs := New(&HandlerOptions{Next: slog.NewTextHandler(&buf, nil), Sample: &SampleOptions{EveryN: 2}})
+		logger := slog.New(handler)
+
+		for i := 0; i < 4; i++ {
+			logger.Info(fmt.Sprintf("log %d", i))
+		}
+
+		assert.Equal(t, 2, logs.Len())
+		assert.Equal(t, 4, strings.Count(buf.String(), "\n"), "sampled out records are still forwarded to Next")
+	})
+
+	t.Run("Rate", func(t *testing.T) {
+		handler, logs := New(&HandlerOptions{Sample: &SampleOptions{Rate: 0.5}})
+		logger := slog.New(handler)
+
+		for i := 0; i < 1000; i++ {
+			logger.Info("log", slog.Int("i", i))
+		}
+
+		records := logs.All()
+		require.NotEmpty(t, records)
+		assert.Equal(t, map[string]any{"i": int64(0)}, records[0].AttrsMap(), "the first record is always kept")
+		assert.Greater(t, len(records), 1)
+		assert.Less(t, len(records), 1000)
+		assert.Equal(t, uint64(1000-len(records)), logs.(DroppedCounter).Dropped())
+	})
+
+	t.Run("keep all", func(t *testing.T) {
+		for _, opts := range []*SampleOptions{nil, {}, {EveryN: 1}, {Rate: 1}} {
+			handler, logs := New(&HandlerOptions{Sample: opts})
+			logger := slog.New(handler)
+
+			for i := 0; i < 5; i++ {
+				logger.Info("log")
+			}
+			assert.Equal(t, 5, logs.Len(), "%+v", opts)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package observer
+
+import "sync"
+
+// subscribers delivers observed records to the subscribed channels.
+// Zero value is ready to use.
+type subscribers struct {
+	mu   sync.Mutex
+	subs map[*subscription]struct{}
+	// buffer is the maximum number of records queued for each subscriber, the oldest queued
+	// records are dropped when it is exceeded. Zero means unbounded queue.
+	buffer int
+}
+
+// setBuffer sets the maximum number of records queued for each new subscriber.
+func (ss *subscribers) setBuffer(n int) {
+	ss.mu.Lock()
+	ss.buffer = n
+	ss.mu.Unlock()
+}
+
+func (ss *subscribers) subscribe() (<-chan LoggedRecord, func()) {
+	ss.mu.Lock()
+	s := &subscription{
+		buffer: ss.buffer,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		ch:     make(chan LoggedRecord),
+	}
+	if ss.subs == nil {
+		ss.subs = make(map[*subscription]struct{})
+	}
+	ss.subs[s] = struct{}{}
+	ss.mu.Unlock()
+
+	go s.run()
+
+	var once sync.Once
+	return s.ch, func() {
+		once.Do(func() {
+			ss.mu.Lock()
+			delete(ss.subs, s)
+			ss.mu.Unlock()
+			close(s.done)
+		})
+	}
+}
+
+// publish queues the record for all the subscribers. It never blocks on slow subscribers,
+// so it is safe to call it while holding the collection lock.
+func (ss *subscribers) publish(r LoggedRecord) {
+	ss.mu.Lock()
+	for s := range ss.subs {
+		s.push(r)
+	}
+	ss.mu.Unlock()
+}
+
+// subscription is a single subscriber with a queue of records that are delivered
+// to the channel by a dedicated goroutine. The queue is unbounded unless buffer is set,
+// in which case the oldest queued records are dropped to make room for the new ones.
+type subscription struct {
+	mu     sync.Mutex
+	queue  []LoggedRecord
+	buffer int
+
+	notify chan struct{}
+	done   chan struct{}
+	ch     chan LoggedRecord
+}
+
+func (s *subscription) push(r LoggedRecord) {
+	s.mu.Lock()
+	if s.buffer > 0 && len(s.queue) >= s.buffer {
+		n := len(s.queue) - s.buffer + 1
+		clear(s.queue[:n])
+		s.queue = s.queue[n:]
+	}
+	s.queue = append(s.queue, r)
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscription) run() {
+	defer close(s.ch)
+
+	for {
+		// take records one by one, so that the ones that are not taken yet can be dropped
+		// when the queue is bounded
+		s.mu.Lock()
+		if len(s.queue) > 0 {
+			r := s.queue[0]
+			s.queue[0] = LoggedRecord{}
+			s.queue = s.queue[1:]
+			s.mu.Unlock()
+
+			select {
+			case s.ch <- r:
+			case <-s.done:
+				return
+			}
+			continue
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.notify:
+		case <-s.done:
+			return
+		}
+	}
+}
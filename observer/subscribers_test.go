@@ -0,0 +1,312 @@
+package observer
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testSubscribe(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testSubscribe(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testSubscribe(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testSubscribe(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(2)})
+	})
+}
+
+func testSubscribe(t *testing.T, ho *HandlerOptions) {
+	const total = 100
+
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	logger.Info("before subscription")
+
+	ch, cancel := logs.(Subscriber).Subscribe()
+	slowCh, slowCancel := logs.(Subscriber).Subscribe()
+
+	// nobody reads from slowCh while logging, logging must not be blocked by it
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < total; i++ {
+			logger.Info("log", slog.Int("i", i))
+		}
+	}()
+
+	for i := 0; i < total; i++ {
+		select {
+		case r := <-ch:
+			assert.Equal(t, map[string]any{"i": int64(i)}, r.AttrsMap())
+		case <-time.After(time.Second):
+			require.FailNow(t, "timed out waiting for the record", "record %d", i)
+		}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow(t, "logging is blocked by the slow subscriber")
+	}
+
+	r := <-slowCh
+	assert.Equal(t, map[string]any{"i": int64(0)}, r.AttrsMap())
+
+	cancel()
+	cancel()
+	slowCancel()
+
+	_, ok := <-ch
+	assert.False(t, ok, "channel must be closed after cancel")
+
+	// slowCh may still have a record that was in flight, but must be closed eventually
+	for range slowCh {
+	}
+
+	logger.Info("after cancel")
+}
+
+func TestSubscribeBuffer(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testSubscribeBuffer(t, &HandlerOptions{SubscriberBuffer: 3})
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testSubscribeBuffer(t, &HandlerOptions{SubscriberBuffer: 3, ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing fixed", func(t *testing.T) {
+		testSubscribeBuffer(t, &HandlerOptions{SubscriberBuffer: 3, ObservedLogs: NewObservedLogsRing(2)})
+	})
+}
+
+func testSubscribeBuffer(t *testing.T, ho *HandlerOptions) {
+	const total = 10
+
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	ch, cancel := logs.(Subscriber).Subscribe()
+	defer cancel()
+
+	// nobody reads from ch while logging, so the oldest queued records are dropped
+	for i := 0; i < total; i++ {
+		logger.Info("log", slog.Int("i", i))
+	}
+
+	var received []int64
+	for len(received) == 0 || received[len(received)-1] != total-1 {
+		select {
+		case r := <-ch:
+			received = append(received, r.AttrsMap()["i"].(int64))
+		case <-time.After(time.Second):
+			require.FailNow(t, "timed out waiting for the record", "received %v", received)
+		}
+	}
+
+	// one record may be already in flight in addition to the queued ones
+	assert.LessOrEqual(t, len(received), 4)
+	assert.Equal(t, []int64{7, 8, 9}, received[len(received)-3:])
+	assert.True(t, slices.IsSorted(received))
+}
+
+func TestSubscribeConcurrent(t *testing.T) {
+	const (
+		writers   = 4
+		perWriter = 100
+	)
+
+	for name, ho := range map[string]*HandlerOptions{
+		"unbounded": nil,
+		"bounded":   {SubscriberBuffer: 5},
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler, logs := New(ho)
+			logger := slog.New(handler)
+
+			// consume reads records until the "done" one that is logged after all the writers are finished,
+			// it is never dropped as it is the latest one, and returns the number of received records
+			consume := func(ch <-chan LoggedRecord, res chan<- int) {
+				last := make(map[int64]int64)
+				n := 0
+				for r := range ch {
+					n++
+					if r.Record.Message == "done" {
+						break
+					}
+
+					attrs := r.AttrsMap()
+					w, i := attrs["w"].(int64), attrs["i"].(int64)
+					if prev, ok := last[w]; ok && prev >= i {
+						t.Errorf("writer %d records are out of order: %d after %d", w, i, prev)
+					}
+					last[w] = i
+				}
+				res <- n
+			}
+
+			ch1, cancel1 := logs.(Subscriber).Subscribe()
+			defer cancel1()
+			ch2, cancel2 := logs.(Subscriber).Subscribe()
+			defer cancel2()
+
+			res1, res2 := make(chan int, 1), make(chan int, 1)
+			go consume(ch1, res1)
+			go consume(ch2, res2)
+
+			var wg sync.WaitGroup
+			for w := 0; w < writers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; i < perWriter; i++ {
+						logger.Info("log", slog.Int("w", w), slog.Int("i", i))
+					}
+				}(w)
+			}
+			wg.Wait()
+			logger.Info("done")
+
+			for _, res := range []chan int{res1, res2} {
+				select {
+				case n := <-res:
+					if ho == nil {
+						assert.Equal(t, writers*perWriter+1, n)
+					} else {
+						assert.LessOrEqual(t, n, writers*perWriter+1)
+					}
+				case <-time.After(5 * time.Second):
+					require.FailNow(t, "timed out waiting for the subscriber")
+				}
+			}
+		})
+	}
+}
+
+func TestSnapshotAndSubscribe(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testSnapshotAndSubscribe(t, nil)
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testSnapshotAndSubscribe(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+}
+
+func testSnapshotAndSubscribe(t *testing.T, ho *HandlerOptions) {
+	const total = 1000
+
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < total; i++ {
+			logger.Info("log", slog.Int("i", i))
+		}
+		logger.Info("done")
+	}()
+
+	// subscribe while the records are being logged, every record must be either in the snapshot
+	// or received from the channel exactly once
+	for logs.Len() < total/10 {
+		runtime.Gosched()
+	}
+	records, ch, cancel := logs.(Subscriber).SnapshotAndSubscribe()
+	defer cancel()
+
+	// the writer may finish before the snapshot is taken
+	finished := len(records) > 0 && records[len(records)-1].Record.Message == "done"
+	for !finished {
+		select {
+		case r := <-ch:
+			records = append(records, r)
+			finished = r.Record.Message == "done"
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for records, received %d", len(records))
+		}
+	}
+	<-done
+
+	records = records[:len(records)-1]
+
+	require.Len(t, records, total)
+	for i, r := range records {
+		require.Equal(t, map[string]any{"i": int64(i)}, r.AttrsMap())
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	t.Run("ObservedLogs not set", func(t *testing.T) {
+		testWaitFor(t, nil)
+	})
+	t.Run("ObservedLogsDefault", func(t *testing.T) {
+		testWaitFor(t, &HandlerOptions{ObservedLogs: NewObservedLogsDefault(0)})
+	})
+	t.Run("ObservedLogsRing", func(t *testing.T) {
+		testWaitFor(t, &HandlerOptions{ObservedLogs: NewObservedLogsRing(0)})
+	})
+	t.Run("not Subscriber", func(t *testing.T) {
+		testWaitFor(t, &HandlerOptions{ObservedLogs: plainLogs{NewObservedLogsDefault(0)}})
+	})
+}
+
+func testWaitFor(t *testing.T, ho *HandlerOptions) {
+	handler, logs := New(ho)
+	logger := slog.New(handler)
+
+	isMessage := func(msg string) func(LoggedRecord) bool {
+		return func(r LoggedRecord) bool {
+			return r.Record.Message == msg
+		}
+	}
+
+	t.Run("already observed", func(t *testing.T) {
+		logger.Info("buffered", slog.Int("i", 1))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		r, found := WaitFor(ctx, logs, isMessage("buffered"))
+		require.True(t, found)
+		assert.Equal(t, map[string]any{"i": int64(1)}, r.AttrsMap())
+	})
+
+	t.Run("logged asynchronously", func(t *testing.T) {
+		go func() {
+			for i := 0; i < 10; i++ {
+				logger.Info("async", slog.Int("i", i))
+			}
+			logger.Info("async done")
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		r, found := WaitFor(ctx, logs, isMessage("async done"))
+		require.True(t, found)
+		assert.Equal(t, "async done", r.Record.Message)
+		assert.Equal(t, 10, logs.FilterMessage("async").Len())
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		r, found := WaitFor(ctx, logs, isMessage("never logged"))
+		assert.False(t, found)
+		assert.Equal(t, LoggedRecord{}, r)
+	})
+}
@@ -0,0 +1,148 @@
+package observer
+
+import (
+	"log/slog"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// TB is the subset of testing.TB used by the test helpers of this package, so that the package does not
+// depend on the testing package. testing.TB implementations, e.g. *testing.T, satisfy it.
+type TB interface {
+	Cleanup(func())
+	Errorf(format string, args ...any)
+	Failed() bool
+	Helper()
+	Logf(format string, args ...any)
+	Name() string
+}
+
+// TestingOption configures the handlers created with NewForTesting and NewT.
+type TestingOption func(*testingOptions)
+
+// testingOptions are shared by the handler created with NewForTesting or NewT and all the derived handlers.
+type testingOptions struct {
+	tb          TB
+	tee         bool
+	alwaysDump  bool
+	failOn      func(LoggedRecord) bool
+	failOnAllow []*regexp.Regexp
+}
+
+// FailOn makes the handler fail the test with TB.Errorf as soon as the record that satisfies fn is observed,
+// e.g. FailOnLevel(slog.LevelError) to treat error logs as bugs. The records with the message matching one
+// of the allow patterns are expected, so they do not fail the test. The failure includes the record level,
+// message and attributes.
+func FailOn(fn func(LoggedRecord) bool, allow ...*regexp.Regexp) TestingOption {
+	return func(o *testingOptions) {
+		o.failOn = fn
+		o.failOnAllow = allow
+	}
+}
+
+// AlwaysDump makes the handler created with NewForTesting dump observed logs when the test is finished
+// even if it did not fail.
+func AlwaysDump() TestingOption {
+	return func(o *testingOptions) {
+		o.alwaysDump = true
+	}
+}
+
+// NewForTesting creates new slog.Handler that buffers logs in memory, same as New does,
+// and registers the test cleanup function that dumps all the observed logs with tb.Logf
+// when the test fails, or always if AlwaysDump option is set. See FailOn to fail the test
+// when the unexpected record is observed.
+func NewForTesting(tb TB, opts *HandlerOptions, testOpts ...TestingOption) (slog.Handler, ObservedLogs) {
+	handler, logs := newTesting(tb, opts, testOpts)
+
+	tb.Cleanup(func() {
+		if !tb.Failed() && !handler.test.alwaysDump {
+			return
+		}
+
+		records := logs.All()
+		tb.Logf("Observed logs (%d):", len(records))
+		for _, r := range records {
+			tb.Logf("%s %-5s %q %v", r.Record.Time.Format(time.RFC3339Nano), r.Record.Level, r.Record.Message, r.AttrsMap())
+		}
+	})
+
+	return handler, logs
+}
+
+// NewT creates new slog.Handler that buffers logs in memory, same as New does, and writes every
+// observed record with tb.Logf as it is handled, so that logs are interleaved with the test output.
+// Records are rendered the same way as LoggedRecord.String does. See FailOn to fail the test
+// when the unexpected record is observed.
+func NewT(tb TB, opts *HandlerOptions, testOpts ...TestingOption) (slog.Handler, ObservedLogs) {
+	handler, logs := newTesting(tb, opts, testOpts)
+	handler.test.tee = true
+
+	return handler, logs
+}
+
+func newTesting(tb TB, opts *HandlerOptions, testOpts []TestingOption) (*contextObserver, ObservedLogs) {
+	handler, logs := New(opts)
+	co := handler.(*contextObserver)
+
+	co.test = &testingOptions{tb: tb}
+	for _, opt := range testOpts {
+		opt(co.test)
+	}
+	return co, logs
+}
+
+// registry holds observers created with ForTest keyed by the test name.
+var registry = struct {
+	mu      sync.Mutex
+	entries map[string]registryEntry
+}{entries: make(map[string]registryEntry)}
+
+type registryEntry struct {
+	handler slog.Handler
+	logs    ObservedLogs
+}
+
+// ForTest returns slog.Handler and ObservedLogs isolated to the test, so that parallel tests and subtests
+// do not see each other logs. The pair is created with NewForTesting on the first call and the same pair
+// is returned for the subsequent calls with the same test, it is removed when the test is finished.
+// Use Lookup to get the pair by the test name.
+func ForTest(tb TB) (slog.Handler, ObservedLogs) {
+	name := tb.Name()
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if e, ok := registry.entries[name]; ok {
+		return e.handler, e.logs
+	}
+
+	handler, logs := NewForTesting(tb, nil)
+	registry.entries[name] = registryEntry{handler: handler, logs: logs}
+	tb.Cleanup(func() {
+		registry.mu.Lock()
+		delete(registry.entries, name)
+		registry.mu.Unlock()
+	})
+
+	return handler, logs
+}
+
+// Lookup returns slog.Handler and ObservedLogs created with ForTest for the test with the specified name,
+// e.g. for the helpers that have only the test name. The last return value is false if there is no such pair,
+// e.g. the test is finished or ForTest was not called for it.
+func Lookup(name string) (slog.Handler, ObservedLogs, bool) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	e, ok := registry.entries[name]
+	return e.handler, e.logs, ok
+}
+
+// FailOnLevel returns the FailOn function that matches the records logged at the level or above.
+func FailOnLevel(level slog.Leveler) func(LoggedRecord) bool {
+	return func(r LoggedRecord) bool {
+		return r.Record.Level >= level.Level()
+	}
+}
@@ -0,0 +1,195 @@
+package observer
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTB is a testing.TB implementation that records failures instead of failing the test.
+type fakeTB struct {
+	testing.TB
+
+	failed   bool
+	logs     []string
+	errs     []string
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.failed = true
+	f.errs = append(f.errs, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Failed() bool {
+	return f.failed
+}
+
+func (f *fakeTB) Logf(format string, args ...any) {
+	f.logs = append(f.logs, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+// cleanup runs registered cleanup functions in the reverse order, the same way testing package does.
+func (f *fakeTB) cleanup() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestNewForTesting(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := func() time.Time { return ts }
+
+	for name, tc := range map[string]struct {
+		opts     *HandlerOptions
+		testOpts []TestingOption
+		failed   bool
+		want     []string
+	}{
+		"passed": {
+			opts: &HandlerOptions{Now: clock},
+		},
+		"passed nil options": {
+			opts: nil,
+		},
+		"failed": {
+			opts:   &HandlerOptions{Now: clock},
+			failed: true,
+			want: []string{
+				"Observed logs (2):",
+				`2024-01-02T03:04:05Z INFO  "starting" map[port:8080]`,
+				`2024-01-02T03:04:05Z ERROR "failed" map[http:map[status:500]]`,
+			},
+		},
+		"passed AlwaysDump": {
+			opts:     &HandlerOptions{Now: clock},
+			testOpts: []TestingOption{AlwaysDump()},
+			want: []string{
+				"Observed logs (2):",
+				`2024-01-02T03:04:05Z INFO  "starting" map[port:8080]`,
+				`2024-01-02T03:04:05Z ERROR "failed" map[http:map[status:500]]`,
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ft := &fakeTB{TB: t}
+			handler, logs := NewForTesting(ft, tc.opts, tc.testOpts...)
+			logger := slog.New(handler)
+
+			logger.Info("starting", slog.Int("port", 8080))
+			logger.Error("failed", slog.Group("http", slog.Int("status", 500)))
+
+			ft.failed = tc.failed
+			ft.cleanup()
+
+			assert.Equal(t, tc.want, ft.logs)
+			assert.Equal(t, 2, logs.Len(), "dump must not consume observed logs")
+		})
+	}
+}
+
+func TestNewForTestingFailOn(t *testing.T) {
+	ft := &fakeTB{TB: t}
+	handler, _ := NewForTesting(ft, nil, FailOn(FailOnLevel(slog.LevelError), regexp.MustCompile(`^expected`)))
+	logger := slog.New(handler).With(slog.String("component", "db"))
+
+	logger.Info("starting")
+	logger.Warn("slow query")
+	logger.Error("expected failure")
+	assert.False(t, ft.failed)
+	assert.Empty(t, ft.errs)
+
+	logger.WithGroup("http").Error("failed", slog.Int("status", 500))
+	assert.True(t, ft.failed)
+	assert.Equal(t, []string{
+		`Unexpected log observed: ERROR "failed" map[component:db http:map[status:500]]`,
+	}, ft.errs)
+}
+
+func TestNewTFailOn(t *testing.T) {
+	ft := &fakeTB{TB: t}
+	handler, _ := NewT(ft, nil, FailOn(FailOnLevel(slog.LevelWarn)))
+	logger := slog.New(handler)
+
+	logger.Info("starting")
+	assert.False(t, ft.failed)
+
+	logger.Warn("slow query")
+	assert.True(t, ft.failed)
+	assert.Equal(t, []string{`Unexpected log observed: WARN "slow query" map[]`}, ft.errs)
+}
+
+func TestNewT(t *testing.T) {
+	ft := &fakeTB{TB: t}
+	handler, logs := NewT(ft, &HandlerOptions{Level: slog.LevelDebug})
+	logger := slog.New(handler).With(slog.String("component", "db"))
+
+	logger.Debug("connecting", slog.String("dsn", "postgres://localhost"))
+	logger.WithGroup("query").Warn("slow", slog.Duration("took", 2*time.Second), slog.Group("", slog.Int("rows", 10)))
+
+	records := logs.All()
+	require.Len(t, records, 2)
+	assert.Equal(t, []string{
+		"level=DEBUG msg=connecting component=db dsn=postgres://localhost",
+		"level=WARN msg=slow component=db query.took=2s query.rows=10",
+	}, ft.logs)
+	assert.Equal(t, []string{records[0].String(), records[1].String()}, ft.logs)
+	assert.False(t, ft.failed)
+}
+
+func TestForTest(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		names []string
+	)
+	t.Run("group", func(t *testing.T) {
+		for i := 0; i < 8; i++ {
+			i := i
+			t.Run(fmt.Sprintf("sub %d", i), func(t *testing.T) {
+				t.Parallel()
+
+				mu.Lock()
+				names = append(names, t.Name())
+				mu.Unlock()
+
+				handler, logs := ForTest(t)
+				logger := slog.New(handler)
+				for j := 0; j < 50; j++ {
+					logger.Info("log", slog.Int("sub", i))
+				}
+
+				sameHandler, sameLogs := ForTest(t)
+				assert.Same(t, handler, sameHandler)
+				assert.Same(t, logs, sameLogs)
+
+				_, lookedUp, ok := Lookup(t.Name())
+				require.True(t, ok)
+				assert.Same(t, logs, lookedUp)
+
+				assert.Equal(t, 50, logs.Len())
+				assert.Equal(t, 50, logs.FilterAttr(slog.Int("sub", i)).Len(), "logs of the other tests must not be observed")
+			})
+		}
+	})
+
+	// entries are removed when the tests are finished
+	require.Len(t, names, 8)
+	for _, name := range names {
+		_, _, ok := Lookup(name)
+		assert.False(t, ok, name)
+	}
+	_, _, ok := Lookup("unknown")
+	assert.False(t, ok)
+}